@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned"
+)
+
+// buildBloom builds a bloom filter from a list of password hashes and
+// persists it, so it can be loaded by the bloom mode of the pwned service.
+func buildBloom(args []string) error {
+
+	flags := flag.NewFlagSet("bloom", flag.ContinueOnError)
+	input := flags.String("in", "", "hash list to build the filter from, one HASH or HASH:COUNT per line")
+	output := flags.String("out", "", "path the bloom filter is written to")
+	falsePositiveRate := flags.Float64("fpr", 0.001, "false positive rate of the filter")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *input == "" || *output == "" {
+		flags.Usage()
+		return fmt.Errorf("both -in and -out are required")
+	}
+
+	log.Printf("Building bloom filter from '%s' with a false positive rate of %g", *input, *falsePositiveRate)
+	filter, err := pwned.BuildBloomFilter(*input, *falsePositiveRate)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	size, err := filter.WriteTo(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Bloom filter written to '%s' (%d bytes)", *output, size)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned"
+)
+
+// buildDataset builds the binary index used by the offline mode of the pwned
+// service, along with a manifest holding its checksum.
+func buildDataset(args []string) error {
+
+	flags := flag.NewFlagSet("dataset", flag.ContinueOnError)
+	output := flags.String("out", "", "path the dataset index is written to")
+	manifestPath := flags.String("manifest", "", "path the checksum manifest is written to (defaults to <out>.manifest.json)")
+	chunkRecords := flags.Int("chunk", 1000000, "records sorted in memory before being spilled to disk")
+	tempDir := flags.String("tmp", "", "directory for the sorted chunks (defaults to the system temporary directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-pwned dataset -out <index> [options] <HASH:COUNT file or range directory>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("-out and at least one input are required")
+	}
+	if *manifestPath == "" {
+		*manifestPath = *output + ".manifest.json"
+	}
+
+	builder := &pwned.DatasetBuilder{
+		ChunkRecords: *chunkRecords,
+		TempDir:      *tempDir,
+		Logf:         log.Printf,
+	}
+	manifest, err := builder.BuildFile(*output, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*manifestPath, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+
+	log.Printf("Dataset written to '%s' (sha256 %s), manifest written to '%s'", *output, manifest.SHA256, *manifestPath)
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+// serveFakeHIBP serves a fake of the range API of Have I Been Pwned, so the
+// service can be run locally without reaching the real one.
+func serveFakeHIBP(args []string) error {
+
+	flags := flag.NewFlagSet("fake-hibp", flag.ContinueOnError)
+	address := flags.String("addr", ":8080", "address to listen on")
+	passwords := flags.String("passwords", "", "file of breached passwords, one PASSWORD or PASSWORD:COUNT per line")
+	latency := flags.Duration("latency", 0, "delay added to every response")
+	errorRate := flags.Float64("error-rate", 0, "rate of requests answered with -error-status, between 0 and 1")
+	errorStatus := flags.Int("error-status", http.StatusServiceUnavailable, "status of the failed requests")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *errorRate < 0 || *errorRate > 1 {
+		return fmt.Errorf("-error-rate must be between 0 and 1")
+	}
+
+	fake := pwnedtest.NewServer()
+	if *passwords != "" {
+		if err := fake.LoadFile(*passwords); err != nil {
+			return err
+		}
+	}
+	fake.SetLatency(*latency)
+	fake.SetFailureRate(*errorRate, *errorStatus)
+
+	server := &http.Server{
+		Addr:              *address,
+		Handler:           fake,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.Printf("Serving fake range API on '%s/range/'", *address)
+	return server.ListenAndServe()
+}
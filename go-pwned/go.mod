@@ -4,6 +4,8 @@ go 1.18
 
 require (
 	github.com/prometheus/client_golang v1.12.1
+	golang.org/x/crypto v0.0.0-20220315160706-3147a52a75dd
+	golang.org/x/text v0.3.7
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -6,19 +6,29 @@ import (
 )
 
 type (
+	// Ready tells whether the service is ready to validate passwords.
+	Ready func() bool
+
 	healthzHandler struct {
-		l *log.Logger
+		l     *log.Logger
+		ready Ready
 	}
 )
 
-func NewHealthzHandler(log *log.Logger) *healthzHandler {
-	return &healthzHandler{log}
+// NewHealthzHandler returns a handler replying 503 while ready reports the
+// service isn't ready yet. A nil ready is always ready.
+func NewHealthzHandler(log *log.Logger, ready Ready) *healthzHandler {
+	return &healthzHandler{log, ready}
 }
 
 func (hh *healthzHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 	if r.Method == http.MethodGet {
 
+		if hh.ready != nil && !hh.ready() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
 		rw.WriteHeader(http.StatusOK)
 	}
 }
@@ -10,7 +10,7 @@ import (
 
 func TestHealthzHandlerShouldReturnOk(t *testing.T) {
 	log := log.New(os.Stdout, "go_test", log.LstdFlags)
-	handler := NewHealthzHandler(log)
+	handler := NewHealthzHandler(log, nil)
 
 	request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 	response := httptest.NewRecorder()
@@ -21,3 +21,38 @@ func TestHealthzHandlerShouldReturnOk(t *testing.T) {
 	}
 
 }
+
+func TestHealthzHandlerShouldReportReadiness(t *testing.T) {
+
+	tests := []struct {
+		scenario       string
+		ready          bool
+		expectedStatus int
+	}{
+		{
+			scenario:       "Should return Ok once ready",
+			ready:          true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			scenario:       "Should return Service Unavailable until ready",
+			ready:          false,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+
+		log := log.New(os.Stdout, "go_test", log.LstdFlags)
+		ready := test.ready
+		handler := NewHealthzHandler(log, func() bool { return ready })
+
+		request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+		if response.Code != test.expectedStatus {
+			t.Errorf("Scenario '%s'. Expected status: '%d', Got: '%d'\n", test.scenario, test.expectedStatus, response.Code)
+		}
+	}
+}
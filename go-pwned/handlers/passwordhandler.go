@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/validations"
 )
 
 const (
@@ -17,7 +19,7 @@ const (
 type (
 	PwnedContextKey string
 
-	ValidatePassword func(password string) (bool, error)
+	ValidatePassword func(ctx context.Context, password string) (bool, error)
 
 	passwordHandler struct {
 		l                *log.Logger
@@ -25,8 +27,14 @@ type (
 		next             http.Handler
 	}
 
+	// passwordRequest carries the password along with optional details of
+	// the user it is for, which it shouldn't resemble.
 	passwordRequest struct {
-		Password string `json:"password"`
+		Password    string `json:"password"`
+		Username    string `json:"username,omitempty"`
+		Email       string `json:"email,omitempty"`
+		DisplayName string `json:"displayName,omitempty"`
+		AppName     string `json:"appName,omitempty"`
 	}
 )
 
@@ -53,7 +61,22 @@ func (ph *passwordHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		ok, err := ph.validatePassword(decodedPassword)
+		// The estimation of the strength of the password is returned by the
+		// next handler along with its response
+		report := &validations.StrengthReport{}
+		ctx := validations.WithStrengthReport(r.Context(), report)
+		ctx = validations.WithUserInfo(ctx, validations.UserInfo{
+			Username:    passwordRequest.Username,
+			Email:       passwordRequest.Email,
+			DisplayName: passwordRequest.DisplayName,
+			AppName:     passwordRequest.AppName,
+		})
+		ok, err := ph.validatePassword(ctx, decodedPassword)
+		if r.Context().Err() != nil {
+			ph.l.Printf("request cancelled while validating password: %s", r.Context().Err())
+			http.Error(rw, "request cancelled before the password could be validated", http.StatusServiceUnavailable)
+			return
+		}
 		if !ok {
 			http.Error(rw, err.Error(), http.StatusBadRequest)
 			elapsed := time.Since(start)
@@ -66,7 +89,7 @@ func (ph *passwordHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 		//if at this stage all validators are correct, invoke next handler
 		if ph.next != nil {
-			context := context.WithValue(r.Context(), PwnedContextKey("UserPassword"), decodedPassword)
+			context := context.WithValue(validations.WithStrengthReport(r.Context(), report), PwnedContextKey("UserPassword"), decodedPassword)
 			r = r.WithContext(context)
 			ph.next.ServeHTTP(rw, r)
 		}
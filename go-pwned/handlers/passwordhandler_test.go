@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/validations"
 )
 
 type TestHandler struct {
@@ -21,7 +24,7 @@ func (tf *TestHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 func TestPasswordHandlerShouldFailWhenRequestCannotBeParsedFromJson(t *testing.T) {
 	log := log.New(os.Stdout, "go_test", log.LstdFlags)
-	validatePasswordFunc := func(password string) (bool, error) { return false, fmt.Errorf("An error") }
+	validatePasswordFunc := func(ctx context.Context, password string) (bool, error) { return false, fmt.Errorf("An error") }
 	handler := NewPasswordHandler(log, validatePasswordFunc, nil)
 	response := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"something": "cnViZW4K"}`))
@@ -34,7 +37,7 @@ func TestPasswordHandlerShouldFailWhenRequestCannotBeParsedFromJson(t *testing.T
 
 func TestPasswordHandlerShouldFailWhenRequestCannotBeDecoded(t *testing.T) {
 	log := log.New(os.Stdout, "go_test", log.LstdFlags)
-	validatePasswordFunc := func(password string) (bool, error) { return false, fmt.Errorf("An error") }
+	validatePasswordFunc := func(ctx context.Context, password string) (bool, error) { return false, fmt.Errorf("An error") }
 	handler := NewPasswordHandler(log, validatePasswordFunc, nil)
 	response := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"password": "---"}`))
@@ -47,7 +50,7 @@ func TestPasswordHandlerShouldFailWhenRequestCannotBeDecoded(t *testing.T) {
 
 func TestPasswordHandlerShouldFailWhenPasswordValidationFails(t *testing.T) {
 	log := log.New(os.Stdout, "go_test", log.LstdFlags)
-	validatePasswordFunc := func(password string) (bool, error) { return false, fmt.Errorf("An error") }
+	validatePasswordFunc := func(ctx context.Context, password string) (bool, error) { return false, fmt.Errorf("An error") }
 	handler := NewPasswordHandler(log, validatePasswordFunc, nil)
 	response := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"password": "cnViZW4K"}`))
@@ -60,7 +63,7 @@ func TestPasswordHandlerShouldFailWhenPasswordValidationFails(t *testing.T) {
 
 func TestPasswordHandlerShouldPassWhenPasswordValidationSucceeds(t *testing.T) {
 	log := log.New(os.Stdout, "go_test", log.LstdFlags)
-	validatePasswordFunc := func(password string) (bool, error) { return true, nil }
+	validatePasswordFunc := func(ctx context.Context, password string) (bool, error) { return true, nil }
 	handler := NewPasswordHandler(log, validatePasswordFunc, nil)
 	response := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"password": "cnViZW4K"}`))
@@ -71,6 +74,27 @@ func TestPasswordHandlerShouldPassWhenPasswordValidationSucceeds(t *testing.T) {
 	}
 }
 
+func TestPasswordHandlerShouldFailWhenRequestIsCancelled(t *testing.T) {
+	log := log.New(os.Stdout, "go_test", log.LstdFlags)
+	ctx, cancel := context.WithCancel(context.Background())
+	validatePasswordFunc := func(ctx context.Context, password string) (bool, error) {
+		cancel()
+		return false, ctx.Err()
+	}
+	next := TestHandler{}
+	handler := NewPasswordHandler(log, validatePasswordFunc, &next)
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"password": "cnViZW4K"}`)).WithContext(ctx)
+
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected ServiceUnavailable got %v\n", response.Code)
+	}
+	if next.serveHTTPCalled {
+		t.Errorf("Expected next handler not to be called for a cancelled request\n")
+	}
+}
+
 func TestPasswordHandlerShouldInvokeNextValidatorIfNotNil(t *testing.T) {
 
 	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
@@ -94,7 +118,7 @@ func TestPasswordHandlerShouldInvokeNextValidatorIfNotNil(t *testing.T) {
 
 	for _, test := range tests {
 
-		validatePasswordFunc := func(password string) (bool, error) { return true, nil }
+		validatePasswordFunc := func(ctx context.Context, password string) (bool, error) { return true, nil }
 
 		var handler http.Handler
 		if test.nextHandler.isNil {
@@ -114,3 +138,72 @@ func TestPasswordHandlerShouldInvokeNextValidatorIfNotNil(t *testing.T) {
 	}
 
 }
+
+func TestPasswordHandlerShouldPassUserDetailsToValidator(t *testing.T) {
+
+	tests := []struct {
+		scenario     string
+		body         string
+		expectedInfo validations.UserInfo
+	}{
+		{
+			scenario: "Should pass every user detail",
+			body:     `{"password": "cnViZW4K", "username": "jsmith", "email": "john.smith@example.com", "displayName": "John Smith", "appName": "Billing"}`,
+			expectedInfo: validations.UserInfo{
+				Username:    "jsmith",
+				Email:       "john.smith@example.com",
+				DisplayName: "John Smith",
+				AppName:     "Billing",
+			},
+		},
+		{
+			scenario: "Should accept requests without user details",
+			body:     `{"password": "cnViZW4K"}`,
+		},
+	}
+
+	for _, test := range tests {
+
+		var info validations.UserInfo
+		validatePasswordFunc := func(ctx context.Context, password string) (bool, error) {
+			info, _ = validations.UserInfoFrom(ctx)
+			return true, nil
+		}
+		handler := NewPasswordHandler(log.New(os.Stdout, "gopwned_test", log.LstdFlags), validatePasswordFunc, nil)
+
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(test.body))
+		handler.ServeHTTP(response, request)
+
+		if response.Code != http.StatusOK {
+			t.Errorf("Scenario '%s'. Expected status %d, Got: %d\n", test.scenario, http.StatusOK, response.Code)
+		}
+		if info != test.expectedInfo {
+			t.Errorf("Scenario '%s'. Expected user details %+v, Got: %+v\n", test.scenario, test.expectedInfo, info)
+		}
+	}
+}
+
+func TestPasswordHandlerShouldPassStrengthToNextHandler(t *testing.T) {
+
+	strength := validations.Strength{Enabled: true}
+	var estimation validations.Estimation
+	var reported bool
+	next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if report, ok := validations.StrengthReportFrom(r.Context()); ok {
+			estimation, reported = report.Estimation()
+		}
+	})
+	handler := NewPasswordHandler(log.New(os.Stdout, "gopwned_test", log.LstdFlags), strength.Validate, next)
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"password": "a1Q5I21RMiF2Ug=="}`))
+	handler.ServeHTTP(response, request)
+
+	if !reported {
+		t.Fatalf("Expected the strength of the password to be passed to the next handler\n")
+	}
+	if expected := validations.Estimate("kT9#mQ2!vR"); estimation.Score != expected.Score {
+		t.Errorf("Expected score %d, Got: %d\n", expected.Score, estimation.Score)
+	}
+}
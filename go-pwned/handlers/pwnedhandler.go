@@ -1,19 +1,55 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/validations"
+	"github.com/jruben-rg/password-service/go-pwned/pwned"
 )
 
-type pwnedHandler struct {
-	log       *log.Logger
-	validator ValidatePassword
-}
+type (
+	CheckPassword func(ctx context.Context, password string) (pwned.Result, error)
+
+	// MetricService records the decisions taken when passwords could not be
+	// checked against breached passwords, and why they couldn't.
+	MetricService interface {
+		DegradedDecision(policy, reason string)
+	}
+
+	pwnedHandler struct {
+		log           *log.Logger
+		checker       CheckPassword
+		failurePolicy pwned.FailurePolicy
+		metrics       MetricService
+	}
+
+	pwnedResponse struct {
+		Occurrences        int               `json:"occurrences"`
+		BreachCheckSkipped bool              `json:"breachCheckSkipped,omitempty"`
+		Warning            string            `json:"warning,omitempty"`
+		Strength           *strengthResponse `json:"strength,omitempty"`
+	}
 
-func NewPwnedHandler(log *log.Logger, validator ValidatePassword) *pwnedHandler {
+	// strengthResponse is how guessable the password is, when its strength
+	// was estimated while validating it.
+	strengthResponse struct {
+		Score            int     `json:"score"`
+		CrackTime        string  `json:"crackTime"`
+		CrackTimeSeconds float64 `json:"crackTimeSeconds"`
+	}
+)
 
-	return &pwnedHandler{log, validator}
+func NewPwnedHandler(log *log.Logger, checker CheckPassword, failurePolicy pwned.FailurePolicy, metrics MetricService) *pwnedHandler {
+
+	if failurePolicy == "" {
+		failurePolicy = pwned.FailClosed
+	}
+	return &pwnedHandler{log, checker, failurePolicy, metrics}
 }
 
 func (pw *pwnedHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
@@ -26,18 +62,87 @@ func (pw *pwnedHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call to Pwned Service
-	isSecure, err := pw.validator(password)
+	result, err := pw.checker(r.Context(), password)
+	if r.Context().Err() != nil {
+		// The client is likely gone, but the password must never be
+		// implicitly accepted, e.g. when cancelled during a shutdown
+		pw.log.Printf("request cancelled while checking password against breached passwords: %s", r.Context().Err())
+		http.Error(rw, "request cancelled before the password could be checked", http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
-		http.Error(rw, "could not verify if password is compromised", http.StatusInternalServerError)
+		pw.handleFailure(rw, r, err)
 		return
 	}
 
-	if !isSecure {
-		http.Error(rw, "insecure password", http.StatusBadRequest)
+	if !result.Secure {
+		message := "insecure password, it has been found in data breaches"
+		if result.Occurrences > 0 {
+			message = fmt.Sprintf("insecure password, it has been seen %d times in data breaches", result.Occurrences)
+		}
+		http.Error(rw, message, http.StatusBadRequest)
 		return
 	}
 
+	response := pwnedResponse{Occurrences: result.Occurrences, Strength: getStrength(r)}
+	if result.Warning {
+		response.Warning = fmt.Sprintf("password has been seen %d times in data breaches", result.Occurrences)
+	}
+
+	writeResponse(rw, response)
+}
+
+// handleFailure applies the failure policy when the password could not be
+// checked against breached passwords.
+func (pw *pwnedHandler) handleFailure(rw http.ResponseWriter, r *http.Request, err error) {
+
+	status, reason := failureStatus(err)
+	pw.log.Printf("could not verify if password is compromised (%s), applying '%s' failure policy: %s", reason, pw.failurePolicy, err)
+	if pw.metrics != nil {
+		pw.metrics.DegradedDecision(string(pw.failurePolicy), reason)
+	}
+
+	switch pw.failurePolicy {
+	case pwned.FailOpen:
+		writeResponse(rw, pwnedResponse{Strength: getStrength(r)})
+	case pwned.FailOpenWithWarning:
+		writeResponse(rw, pwnedResponse{
+			BreachCheckSkipped: true,
+			Warning:            "could not verify if password is compromised, breach check was skipped",
+			Strength:           getStrength(r),
+		})
+	default:
+		http.Error(rw, "could not verify if password is compromised", status)
+	}
+}
+
+// failureStatus maps the reason why a password could not be checked to the
+// status the request is rejected with when failing closed.
+func failureStatus(err error) (int, string) {
+
+	var statusErr *pwned.ErrUpstreamStatus
+	switch {
+	case errors.Is(err, pwned.ErrTimeout):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.Is(err, pwned.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, "circuit_open"
+	case errors.Is(err, pwned.ErrThrottled):
+		return http.StatusServiceUnavailable, "throttled"
+	case errors.As(err, &statusErr):
+		return http.StatusBadGateway, fmt.Sprintf("status_%d", statusErr.Code)
+	case errors.Is(err, pwned.ErrTransport):
+		return http.StatusBadGateway, "transport"
+	case errors.Is(err, pwned.ErrRead):
+		return http.StatusBadGateway, "read"
+	}
+
+	return http.StatusInternalServerError, "error"
+}
+
+func writeResponse(rw http.ResponseWriter, response pwnedResponse) {
+	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(response)
 }
 
 func getPassword(r *http.Request) (string, error) {
@@ -52,3 +157,22 @@ func getPassword(r *http.Request) (string, error) {
 
 	return password, nil
 }
+
+// getStrength returns the estimation of the strength of the password reported
+// by the password handler, or nil if it wasn't estimated.
+func getStrength(r *http.Request) *strengthResponse {
+
+	report, ok := validations.StrengthReportFrom(r.Context())
+	if !ok {
+		return nil
+	}
+	estimation, ok := report.Estimation()
+	if !ok {
+		return nil
+	}
+	return &strengthResponse{
+		Score:            estimation.Score,
+		CrackTime:        estimation.CrackTime(),
+		CrackTimeSeconds: estimation.CrackTimeSeconds,
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,6 +10,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/validations"
+	"github.com/jruben-rg/password-service/go-pwned/pwned"
 )
 
 type TestPwnedValidator struct {
@@ -17,9 +21,19 @@ type TestPwnedValidator struct {
 	HasBeenInvoked bool
 }
 
-func (pv *TestPwnedValidator) TestValidatePassword(password string) (bool, error) {
+type TestMetricService struct {
+	degradedDecisions []string
+	degradedReasons   []string
+}
+
+func (tm *TestMetricService) DegradedDecision(policy, reason string) {
+	tm.degradedDecisions = append(tm.degradedDecisions, policy)
+	tm.degradedReasons = append(tm.degradedReasons, reason)
+}
+
+func (pv *TestPwnedValidator) TestCheckPassword(ctx context.Context, password string) (pwned.Result, error) {
 	pv.HasBeenInvoked = true
-	return pv.ReturnIsSecure, pv.ReturnError
+	return pwned.Result{Secure: pv.ReturnIsSecure}, pv.ReturnError
 }
 
 func TestRetrieveUserPasswordShouldReturnError(t *testing.T) {
@@ -136,7 +150,7 @@ func TestPwnedHandler(t *testing.T) {
 	for _, test := range tests {
 
 		validator := TestPwnedValidator{ReturnIsSecure: test.validatorIsSecure, ReturnError: test.validatorError}
-		handler := NewPwnedHandler(log, validator.TestValidatePassword)
+		handler := NewPwnedHandler(log, validator.TestCheckPassword, pwned.FailClosed, nil)
 
 		//Create new request and response
 		request := httptest.NewRequest("POST", "/validate", nil)
@@ -162,3 +176,318 @@ func TestPwnedHandler(t *testing.T) {
 	}
 
 }
+
+func TestPwnedHandlerShouldReportOccurrences(t *testing.T) {
+
+	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
+
+	tests := []struct {
+		scenario             string
+		result               pwned.Result
+		expectedResponseCode int
+		expectedBody         string
+		expectedResponse     pwnedResponse
+	}{
+		{
+			scenario:             "Should report the occurrences of a rejected password",
+			result:               pwned.Result{Secure: false, Occurrences: 120},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedBody:         "insecure password, it has been seen 120 times in data breaches",
+		},
+		{
+			scenario:             "Should reject a password whose occurrences are unknown",
+			result:               pwned.Result{Secure: false},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedBody:         "insecure password, it has been found in data breaches",
+		},
+		{
+			scenario:             "Should report the occurrences of an accepted password",
+			result:               pwned.Result{Secure: true, Occurrences: 3},
+			expectedResponseCode: http.StatusOK,
+			expectedResponse:     pwnedResponse{Occurrences: 3},
+		},
+		{
+			scenario:             "Should report a warning for an accepted password",
+			result:               pwned.Result{Secure: true, Occurrences: 3, Warning: true},
+			expectedResponseCode: http.StatusOK,
+			expectedResponse:     pwnedResponse{Occurrences: 3, Warning: "password has been seen 3 times in data breaches"},
+		},
+	}
+
+	for _, test := range tests {
+
+		result := test.result
+		handler := NewPwnedHandler(log, func(ctx context.Context, password string) (pwned.Result, error) { return result, nil }, pwned.FailClosed, nil)
+
+		request := httptest.NewRequest("POST", "/validate", nil)
+		request = request.WithContext(context.WithValue(request.Context(), PwnedContextKey("UserPassword"), "Passw0rd"))
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		if response.Code != test.expectedResponseCode {
+			t.Errorf("Scenario '%s'. Expected Response Code: %d. Got: %d.\n", test.scenario, test.expectedResponseCode, response.Code)
+		}
+
+		if test.expectedResponseCode != http.StatusOK {
+			if body := strings.TrimSpace(response.Body.String()); body != test.expectedBody {
+				t.Errorf("Scenario '%s'. Expected body: '%s'. Got: '%s'.\n", test.scenario, test.expectedBody, body)
+			}
+			continue
+		}
+
+		var pwnedResponse pwnedResponse
+		if err := json.NewDecoder(response.Body).Decode(&pwnedResponse); err != nil {
+			t.Errorf("Scenario '%s'. Could not decode response: %s\n", test.scenario, err)
+		}
+		if pwnedResponse != test.expectedResponse {
+			t.Errorf("Scenario '%s'. Expected response: %+v. Got: %+v.\n", test.scenario, test.expectedResponse, pwnedResponse)
+		}
+	}
+}
+
+func TestPwnedHandlerShouldReportStrength(t *testing.T) {
+
+	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
+
+	tests := []struct {
+		scenario         string
+		strength         validations.Strength
+		err              error
+		failurePolicy    pwned.FailurePolicy
+		expectedStrength bool
+	}{
+		{
+			scenario:         "Should report the strength of an accepted password",
+			strength:         validations.Strength{Enabled: true},
+			expectedStrength: true,
+		},
+		{
+			scenario:         "Should report the strength of a password accepted failing open",
+			strength:         validations.Strength{Enabled: true},
+			err:              fmt.Errorf("test validator error"),
+			failurePolicy:    pwned.FailOpenWithWarning,
+			expectedStrength: true,
+		},
+		{
+			scenario:         "Should not report the strength when it wasn't estimated",
+			strength:         validations.Strength{Enabled: false},
+			expectedStrength: false,
+		},
+	}
+
+	for _, test := range tests {
+
+		validator := TestPwnedValidator{ReturnIsSecure: true, ReturnError: test.err}
+		handler := NewPwnedHandler(log, validator.TestCheckPassword, test.failurePolicy, nil)
+
+		report := &validations.StrengthReport{}
+		ctx := validations.WithStrengthReport(context.Background(), report)
+		test.strength.Validate(ctx, "kT9#mQ2!vR")
+
+		request := httptest.NewRequest("POST", "/validate", nil)
+		request = request.WithContext(context.WithValue(ctx, PwnedContextKey("UserPassword"), "kT9#mQ2!vR"))
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		if response.Code != http.StatusOK {
+			t.Errorf("Scenario '%s'. Expected Response Code: %d. Got: %d.\n", test.scenario, http.StatusOK, response.Code)
+		}
+
+		var pwnedResponse pwnedResponse
+		if err := json.NewDecoder(response.Body).Decode(&pwnedResponse); err != nil {
+			t.Errorf("Scenario '%s'. Could not decode response: %s\n", test.scenario, err)
+		}
+		if (pwnedResponse.Strength != nil) != test.expectedStrength {
+			t.Errorf("Scenario '%s'. Expected strength to be reported: %t. Got: %+v.\n", test.scenario, test.expectedStrength, pwnedResponse.Strength)
+			continue
+		}
+
+		if expected := validations.Estimate("kT9#mQ2!vR"); test.expectedStrength && (pwnedResponse.Strength.Score != expected.Score || pwnedResponse.Strength.CrackTime != expected.CrackTime()) {
+			t.Errorf("Scenario '%s'. Expected score %d and crack time '%s'. Got: %+v.\n", test.scenario, expected.Score, expected.CrackTime(), pwnedResponse.Strength)
+		}
+	}
+}
+
+func TestPwnedHandlerShouldApplyFailurePolicy(t *testing.T) {
+
+	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
+
+	tests := []struct {
+		scenario             string
+		failurePolicy        pwned.FailurePolicy
+		expectedResponseCode int
+		expectedResponse     pwnedResponse
+		expectedPolicy       string
+	}{
+		{
+			scenario:             "Should reject the password when no policy is set",
+			failurePolicy:        "",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedPolicy:       "closed",
+		},
+		{
+			scenario:             "Should reject the password when failing closed",
+			failurePolicy:        pwned.FailClosed,
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedPolicy:       "closed",
+		},
+		{
+			scenario:             "Should accept the password when failing open",
+			failurePolicy:        pwned.FailOpen,
+			expectedResponseCode: http.StatusOK,
+			expectedResponse:     pwnedResponse{},
+			expectedPolicy:       "open",
+		},
+		{
+			scenario:             "Should accept the password flagging the skipped check when failing open with warning",
+			failurePolicy:        pwned.FailOpenWithWarning,
+			expectedResponseCode: http.StatusOK,
+			expectedResponse: pwnedResponse{
+				BreachCheckSkipped: true,
+				Warning:            "could not verify if password is compromised, breach check was skipped",
+			},
+			expectedPolicy: "open-with-warning",
+		},
+	}
+
+	for _, test := range tests {
+
+		validator := TestPwnedValidator{ReturnError: fmt.Errorf("test validator error")}
+		metrics := &TestMetricService{}
+		handler := NewPwnedHandler(log, validator.TestCheckPassword, test.failurePolicy, metrics)
+
+		request := httptest.NewRequest("POST", "/validate", nil)
+		request = request.WithContext(context.WithValue(request.Context(), PwnedContextKey("UserPassword"), "Passw0rd"))
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		if response.Code != test.expectedResponseCode {
+			t.Errorf("Scenario '%s'. Expected Response Code: %d. Got: %d.\n", test.scenario, test.expectedResponseCode, response.Code)
+		}
+
+		if len(metrics.degradedDecisions) != 1 || metrics.degradedDecisions[0] != test.expectedPolicy {
+			t.Errorf("Scenario '%s'. Expected one degraded decision for policy '%s'. Got: %v.\n", test.scenario, test.expectedPolicy, metrics.degradedDecisions)
+		}
+
+		if test.expectedResponseCode != http.StatusOK {
+			continue
+		}
+
+		var pwnedResponse pwnedResponse
+		if err := json.NewDecoder(response.Body).Decode(&pwnedResponse); err != nil {
+			t.Errorf("Scenario '%s'. Could not decode response: %s\n", test.scenario, err)
+		}
+		if pwnedResponse != test.expectedResponse {
+			t.Errorf("Scenario '%s'. Expected response: %+v. Got: %+v.\n", test.scenario, test.expectedResponse, pwnedResponse)
+		}
+	}
+}
+
+func TestPwnedHandlerShouldMapUpstreamErrors(t *testing.T) {
+
+	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
+
+	tests := []struct {
+		scenario             string
+		err                  error
+		expectedResponseCode int
+		expectedReason       string
+	}{
+		{
+			scenario:             "Should respond with GatewayTimeout (504) if pwned service timed out",
+			err:                  fmt.Errorf("provider 'remote': %w", pwned.ErrTimeout),
+			expectedResponseCode: http.StatusGatewayTimeout,
+			expectedReason:       "timeout",
+		},
+		{
+			scenario:             "Should respond with BadGateway (502) if pwned service replied with an error",
+			err:                  &pwned.ErrUpstreamStatus{Code: http.StatusTooManyRequests},
+			expectedResponseCode: http.StatusBadGateway,
+			expectedReason:       "status_429",
+		},
+		{
+			scenario:             "Should respond with BadGateway (502) if pwned service could not be reached",
+			err:                  fmt.Errorf("dial: %w", pwned.ErrTransport),
+			expectedResponseCode: http.StatusBadGateway,
+			expectedReason:       "transport",
+		},
+		{
+			scenario:             "Should respond with BadGateway (502) if pwned response could not be read",
+			err:                  pwned.ErrRead,
+			expectedResponseCode: http.StatusBadGateway,
+			expectedReason:       "read",
+		},
+		{
+			scenario:             "Should respond with ServiceUnavailable (503) if circuit breaker is open",
+			err:                  pwned.ErrCircuitOpen,
+			expectedResponseCode: http.StatusServiceUnavailable,
+			expectedReason:       "circuit_open",
+		},
+		{
+			scenario:             "Should respond with ServiceUnavailable (503) if requests are throttled",
+			err:                  pwned.ErrThrottled,
+			expectedResponseCode: http.StatusServiceUnavailable,
+			expectedReason:       "throttled",
+		},
+		{
+			scenario:             "Should respond with InternalServerError (500) for any other error",
+			err:                  fmt.Errorf("offline dataset is not loaded"),
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedReason:       "error",
+		},
+	}
+
+	for _, test := range tests {
+
+		validator := TestPwnedValidator{ReturnError: test.err}
+		metrics := &TestMetricService{}
+		handler := NewPwnedHandler(log, validator.TestCheckPassword, pwned.FailClosed, metrics)
+
+		request := httptest.NewRequest("POST", "/validate", nil)
+		request = request.WithContext(context.WithValue(request.Context(), PwnedContextKey("UserPassword"), "Passw0rd"))
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		if response.Code != test.expectedResponseCode {
+			t.Errorf("Scenario '%s'. Expected Response Code: %d. Got: %d.\n", test.scenario, test.expectedResponseCode, response.Code)
+		}
+		if len(metrics.degradedReasons) != 1 || metrics.degradedReasons[0] != test.expectedReason {
+			t.Errorf("Scenario '%s'. Expected one degraded decision for reason '%s'. Got: %v.\n", test.scenario, test.expectedReason, metrics.degradedReasons)
+		}
+	}
+}
+
+func TestPwnedHandlerShouldPropagateRequestContext(t *testing.T) {
+
+	log := log.New(os.Stdout, "gopwned_test", log.LstdFlags)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var checkedCtx context.Context
+	checker := func(ctx context.Context, password string) (pwned.Result, error) {
+		checkedCtx = ctx
+		cancel()
+		return pwned.Result{}, ctx.Err()
+	}
+	metrics := &TestMetricService{}
+	handler := NewPwnedHandler(log, checker, pwned.FailClosed, metrics)
+
+	request := httptest.NewRequest("POST", "/validate", nil)
+	request = request.WithContext(context.WithValue(ctx, PwnedContextKey("UserPassword"), "Passw0rd"))
+	response := httptest.NewRecorder()
+
+	handler.ServeHTTP(response, request)
+
+	if checkedCtx == nil || checkedCtx.Value(PwnedContextKey("UserPassword")) != "Passw0rd" {
+		t.Error("Expected the password to be checked with the request context")
+	}
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected Response Code: %d for a cancelled request. Got: %d.\n", http.StatusServiceUnavailable, response.Code)
+	}
+	if len(metrics.degradedDecisions) != 0 {
+		t.Errorf("Expected no degraded decision for a cancelled request. Got: %v.\n", metrics.degradedDecisions)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,6 +17,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// commands are run instead of the service when their name is given as the
+// first argument.
+var commands = map[string]func(args []string) error{
+	"bloom":     buildBloom,
+	"dataset":   buildDataset,
+	"fake-hibp": serveFakeHIBP,
+}
+
 func init() {
 	// Change logging to stdout
 	log.SetOutput(os.Stdout)
@@ -29,19 +38,26 @@ func main() {
 		log.Fatal("provide a path for a yaml file so configuration can be loaded")
 	}
 
-	//Initialise pwned and password validators
-	passwordValidator := password.NewPasswordConfig(os.Args[1])
-	pwnedValidator := pwned.NewPwnedConfig(os.Args[1])
+	if command, ok := commands[os.Args[1]]; ok {
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	metricsService, err := metric.NewPrometheusService()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	//Initialise pwned and password validators
+	passwordValidator := password.NewPasswordConfig(os.Args[1])
+	pwnedValidator := pwned.NewPwnedConfig(os.Args[1], metricsService)
+
 	//Chain handlers
-	pwnedHandler := handlers.NewPwnedHandler(log, pwnedValidator.IsSecurePassword)
+	pwnedHandler := handlers.NewPwnedHandler(log, pwnedValidator.Check, pwnedValidator.FailurePolicy, metricsService)
 	passwordHandler := handlers.NewPasswordHandler(log, passwordValidator.Validate, pwnedHandler)
-	healthtzHandler := handlers.NewHealthzHandler(log)
+	healthtzHandler := handlers.NewHealthzHandler(log, pwnedValidator.Ready)
 
 	mux := http.NewServeMux()
 	mux.Handle("/validate", passwordHandler)
@@ -49,6 +65,11 @@ func main() {
 	mux.Handle("/metrics", promhttp.Handler())
 	wrappedMux := middleware.Metrics(metricsService, mux)
 
+	// Requests are served with a context cancelled once the service gives up
+	// on shutting down gracefully, so calls still in flight are aborted.
+	baseContext, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	s := &http.Server{
 		Addr:              ":2112",
 		Handler:           wrappedMux,
@@ -57,8 +78,12 @@ func main() {
 		WriteTimeout:      1 * time.Second,
 		IdleTimeout:       120 * time.Second,
 		MaxHeaderBytes:    0,
+		BaseContext:       func(net.Listener) context.Context { return baseContext },
 	}
 
+	// The warm-up runs until the service shuts down
+	pwnedValidator.StartWarmUp(baseContext)
+
 	go func() {
 		err := s.ListenAndServe()
 		if err != nil {
@@ -66,7 +91,7 @@ func main() {
 		}
 	}()
 
-	sigChan := make(chan os.Signal)
+	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	signal.Notify(sigChan, os.Kill)
 
@@ -75,5 +100,8 @@ func main() {
 
 	tc, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancelFunc()
-	s.Shutdown(tc)
+	if err := s.Shutdown(tc); err != nil {
+		log.Println("Could not shutdown gracefully, cancelling requests in flight", err)
+		cancelRequests()
+	}
 }
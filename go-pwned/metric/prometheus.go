@@ -6,6 +6,16 @@ import (
 
 type PrometheusService struct {
 	httpRequestHistogram *prometheus.HistogramVec
+	cacheHits            prometheus.Counter
+	cacheMisses          prometheus.Counter
+	cacheEvictions       prometheus.Counter
+	breakerState         prometheus.Gauge
+	degradedDecisions    *prometheus.CounterVec
+	retries              *prometheus.CounterVec
+	warmUpProcessed      prometheus.Gauge
+	warmUpTotal          prometheus.Gauge
+	warmUpFailures       prometheus.Counter
+	throttled            prometheus.Counter
 }
 
 func NewPrometheusService() (*PrometheusService, error) {
@@ -18,11 +28,36 @@ func NewPrometheusService() (*PrometheusService, error) {
 
 	s := &PrometheusService{
 		httpRequestHistogram: http,
+		cacheHits:            newPwnedCounter("cache", "hits_total", "The number of pwned range lookups served from cache."),
+		cacheMisses:          newPwnedCounter("cache", "misses_total", "The number of pwned range lookups not found in cache."),
+		cacheEvictions:       newPwnedCounter("cache", "evictions_total", "The number of pwned range entries evicted from cache."),
+		breakerState: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "pwned",
+			Subsystem: "circuit_breaker",
+			Name:      "state",
+			Help:      "The state of the pwned service circuit breaker (0 closed, 1 open, 2 half-open).",
+		}),
+		degradedDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pwned",
+			Name:      "degraded_decisions_total",
+			Help:      "The number of passwords decided by the failure policy as they could not be checked.",
+		}, []string{"policy", "reason"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pwned",
+			Name:      "retries_total",
+			Help:      "The number of requests to the pwned service retried.",
+		}, []string{"reason"}),
+		warmUpProcessed: newPwnedGauge("warmup", "prefixes_processed", "The number of prefixes processed by the current warm-up pass."),
+		warmUpTotal:     newPwnedGauge("warmup", "prefixes", "The number of prefixes fetched by each warm-up pass."),
+		warmUpFailures:  newPwnedCounter("warmup", "failures_total", "The number of prefixes the warm-up could not fetch."),
+		throttled:       newPwnedCounter("", "throttled_total", "The number of requests to the pwned service rejected by the rate limit."),
 	}
 
-	err := prometheus.Register(s.httpRequestHistogram)
-	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
-		return nil, err
+	collectors := []prometheus.Collector{s.httpRequestHistogram, s.cacheHits, s.cacheMisses, s.cacheEvictions, s.breakerState, s.degradedDecisions, s.retries, s.warmUpProcessed, s.warmUpTotal, s.warmUpFailures, s.throttled}
+	for _, collector := range collectors {
+		if err := register(collector); err != nil {
+			return nil, err
+		}
 	}
 	return s, nil
 }
@@ -30,3 +65,66 @@ func NewPrometheusService() (*PrometheusService, error) {
 func (ps *PrometheusService) SaveMetrics(mi *MetricInfo) {
 	ps.httpRequestHistogram.WithLabelValues(mi.Path, mi.Method, mi.StatusCode, mi.Job).Observe(mi.Duration)
 }
+
+func (ps *PrometheusService) CacheHit() {
+	ps.cacheHits.Inc()
+}
+
+func (ps *PrometheusService) CacheMiss() {
+	ps.cacheMisses.Inc()
+}
+
+func (ps *PrometheusService) CacheEviction() {
+	ps.cacheEvictions.Inc()
+}
+
+func (ps *PrometheusService) CircuitBreakerState(state int) {
+	ps.breakerState.Set(float64(state))
+}
+
+func (ps *PrometheusService) DegradedDecision(policy, reason string) {
+	ps.degradedDecisions.WithLabelValues(policy, reason).Inc()
+}
+
+func (ps *PrometheusService) Retry(reason string) {
+	ps.retries.WithLabelValues(reason).Inc()
+}
+
+func (ps *PrometheusService) WarmUpProgress(processed, total int) {
+	ps.warmUpProcessed.Set(float64(processed))
+	ps.warmUpTotal.Set(float64(total))
+}
+
+func (ps *PrometheusService) WarmUpFailure() {
+	ps.warmUpFailures.Inc()
+}
+
+func (ps *PrometheusService) Throttled() {
+	ps.throttled.Inc()
+}
+
+func newPwnedCounter(subsystem, name, help string) prometheus.Counter {
+	return prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "pwned",
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	})
+}
+
+func newPwnedGauge(subsystem, name, help string) prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "pwned",
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	})
+}
+
+func register(collector prometheus.Collector) error {
+	err := prometheus.Register(collector)
+	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package normalize turns passwords into the canonical variants users hide
+// them behind, so rules looking passwords up in lists of words or breached
+// passwords can find P@55w0rd or its Cyrillic look-alike as Password.
+package normalize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Characters standing for several letters make leet variants grow
+	// quickly, so only the first ones are returned.
+	maxLeetVariants = 8
+	// MaxLength is the length in characters of the longest password whose
+	// variants are returned, bounding the work of rules looking them up.
+	MaxLength = 256
+)
+
+type (
+	// Normalization selects the variants of a password a rule looks up,
+	// besides the password itself.
+	Normalization struct {
+		// NFKC folds compatibility characters, as fullwidth or ligatures
+		NFKC bool `yaml:"nfkc"`
+		// Confusables replaces letters of other scripts looking like latin
+		// ones, as Cyrillic а, and drops accents
+		Confusables bool `yaml:"confusables"`
+		// Leet replaces digits and symbols standing for letters
+		Leet bool `yaml:"leet"`
+	}
+)
+
+var (
+	// leetTable lists the letters every leet character may stand for.
+	leetTable = map[rune][]rune{
+		'4': {'a'}, '@': {'a'},
+		'8': {'b'},
+		'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+		'3': {'e'},
+		'6': {'g'}, '9': {'g'},
+		'#': {'h'},
+		'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+		'0': {'o'},
+		'$': {'s'}, '5': {'s'},
+		'7': {'t'}, '+': {'t'},
+		'%': {'x'},
+		'2': {'z'},
+	}
+
+	// confusables maps letters of other scripts to the latin letters they
+	// look like.
+	confusables = map[rune]rune{
+		// Cyrillic
+		'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+		'ѕ': 's', 'і': 'i', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q', 'ԝ': 'w',
+		'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+		'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'У': 'Y', 'Ѕ': 'S', 'І': 'I',
+		'Ј': 'J',
+		// Greek
+		'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'υ': 'u',
+		'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K',
+		'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+		// Latin
+		'ı': 'i', 'ȷ': 'j', 'ɑ': 'a', 'ɡ': 'g', 'ℓ': 'l',
+	}
+)
+
+// Enabled tells whether any variant is selected.
+func (n Normalization) Enabled() bool {
+	return n.NFKC || n.Confusables || n.Leet
+}
+
+// Variants returns password followed by its selected variants, without
+// duplicates. Leet substitutions are applied on top of the other variants.
+// Passwords longer than MaxLength are returned alone.
+func (n Normalization) Variants(password string) []string {
+
+	variants := []string{password}
+	if utf8.RuneCountInString(password) > MaxLength {
+		return variants
+	}
+	seen := map[string]bool{password: true}
+	add := func(variant string) {
+		if !seen[variant] {
+			seen[variant] = true
+			variants = append(variants, variant)
+		}
+	}
+
+	if n.NFKC {
+		add(NFKC(password))
+	}
+	if n.Confusables {
+		add(Skeleton(password))
+	}
+	if n.Leet {
+		for _, variant := range variants {
+			for _, leet := range Leet(variant) {
+				add(leet)
+			}
+		}
+	}
+
+	return variants
+}
+
+// NFKC returns the compatibility composition of password, so fullwidth Ｐ or
+// the ﬁ ligature are read as P and fi.
+func NFKC(password string) string {
+	return norm.NFKC.String(password)
+}
+
+// Skeleton returns password with its letters replaced by the latin letters
+// they look like, and without accents.
+func Skeleton(password string) string {
+
+	var skeleton strings.Builder
+	for _, char := range norm.NFKD.String(password) {
+		if unicode.Is(unicode.Mn, char) {
+			continue
+		}
+		if latin, ok := confusables[char]; ok {
+			char = latin
+		}
+		skeleton.WriteRune(char)
+	}
+	return norm.NFC.String(skeleton.String())
+}
+
+// LeetLetters returns the letters char may stand for when used in leet, or
+// false if it isn't a leet character. The letters must not be modified.
+func LeetLetters(char rune) ([]rune, bool) {
+	letters, ok := leetTable[char]
+	return letters, ok
+}
+
+// Leet returns the words password could stand for replacing the digits and
+// symbols used as letters, as p@55w0rd for password. Only passwords mixing
+// letters with such characters are taken as leet, so plain numbers aren't.
+func Leet(password string) []string {
+
+	hasLetter, hasLeet := false, false
+	for _, char := range password {
+		hasLetter = hasLetter || unicode.IsLetter(char)
+		_, ok := leetTable[char]
+		hasLeet = hasLeet || ok
+	}
+	if !hasLetter || !hasLeet {
+		return nil
+	}
+
+	// The letters every character may stand for, the variants being every
+	// combination of them, in order
+	choices := make([][]rune, 0, len(password))
+	count := 1
+	for _, char := range password {
+		letters, ok := leetTable[char]
+		if !ok {
+			letters = []rune{char}
+		}
+		choices = append(choices, letters)
+		if count < maxLeetVariants {
+			count *= len(letters)
+		}
+	}
+	if count > maxLeetVariants {
+		count = maxLeetVariants
+	}
+
+	leets := make([]string, count)
+	for k := range leets {
+		// k written in the mixed radix of the choices picks the letters
+		picks := make([]rune, len(choices))
+		for i, rest := len(choices)-1, k; i >= 0; i-- {
+			picks[i] = choices[i][rest%len(choices[i])]
+			rest /= len(choices[i])
+		}
+		leets[k] = string(picks)
+	}
+	return leets
+}
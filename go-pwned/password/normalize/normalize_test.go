@@ -0,0 +1,201 @@
+package normalize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLeet(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		password string
+		expected []string
+	}{
+		{
+			scenario: "Should replace symbols standing for letters",
+			password: "P@$$word",
+			expected: []string{"Password"},
+		},
+		{
+			scenario: "Should replace digits standing for letters",
+			password: "P455w0rd",
+			expected: []string{"Password"},
+		},
+		{
+			scenario: "Should try every letter a character may stand for",
+			password: "1etmein",
+			expected: []string{"ietmein", "letmein"},
+		},
+		{
+			scenario: "Should replace brackets and other symbols standing for letters",
+			password: "<0mpu7er#",
+			expected: []string{"computerh"},
+		},
+		{
+			scenario: "Should replace every substitution at once",
+			password: "7r0ub4d0ur",
+			expected: []string{"troubadour"},
+		},
+		{
+			scenario: "Should not take numbers as leet",
+			password: "123456",
+		},
+		{
+			scenario: "Should not take passwords without substitutions as leet",
+			password: "password",
+		},
+	}
+
+	for _, test := range tests {
+		assertVariants(t, test.scenario, Leet(test.password), test.expected)
+	}
+}
+
+func TestLeetShouldLimitVariants(t *testing.T) {
+
+	if variants := Leet("a1111111111"); len(variants) != maxLeetVariants {
+		t.Errorf("Expected %d variants, Got: %d\n", maxLeetVariants, len(variants))
+	}
+}
+
+func TestLeetShouldHandleLongPasswords(t *testing.T) {
+
+	password := strings.Repeat("a1", 30000)
+	variants := Leet(password)
+	if len(variants) != maxLeetVariants {
+		t.Fatalf("Expected %d variants, Got: %d\n", maxLeetVariants, len(variants))
+	}
+	if expected := "ai" + strings.Repeat("ai", 29999); variants[0] != expected {
+		t.Errorf("Expected the first variant to replace every substitution by its first letter\n")
+	}
+}
+
+func TestVariantsShouldSkipLongPasswords(t *testing.T) {
+
+	password := strings.Repeat("P@55", MaxLength/4+1)
+	variants := Normalization{NFKC: true, Confusables: true, Leet: true}.Variants(password)
+	if len(variants) != 1 || variants[0] != password {
+		t.Errorf("Expected only the password itself, Got: %d variants\n", len(variants))
+	}
+}
+
+func TestNFKC(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		password string
+		expected string
+	}{
+		{
+			scenario: "Should fold fullwidth characters",
+			password: "Ｐａｓｓｗｏｒｄ１",
+			expected: "Password1",
+		},
+		{
+			scenario: "Should fold ligatures",
+			password: "ﬁnance",
+			expected: "finance",
+		},
+		{
+			scenario: "Should keep composed characters",
+			password: "contraseña",
+			expected: "contraseña",
+		},
+	}
+
+	for _, test := range tests {
+		if normalized := NFKC(test.password); normalized != test.expected {
+			t.Errorf("Scenario '%s'. Expected: '%s', Got: '%s'\n", test.scenario, test.expected, normalized)
+		}
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		password string
+		expected string
+	}{
+		{
+			scenario: "Should replace Cyrillic look-alikes",
+			password: "Раѕѕwоrd",
+			expected: "Password",
+		},
+		{
+			scenario: "Should replace Greek look-alikes",
+			password: "ΑΒΟΡΤ",
+			expected: "ABOPT",
+		},
+		{
+			scenario: "Should drop accents",
+			password: "pässwörd",
+			expected: "password",
+		},
+		{
+			scenario: "Should keep latin passwords",
+			password: "Password1!",
+			expected: "Password1!",
+		},
+	}
+
+	for _, test := range tests {
+		if skeleton := Skeleton(test.password); skeleton != test.expected {
+			t.Errorf("Scenario '%s'. Expected: '%s', Got: '%s'\n", test.scenario, test.expected, skeleton)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		normalization Normalization
+		password      string
+		expected      []string
+	}{
+		{
+			scenario: "Should only return the password without normalization",
+			password: "P@55w0rd",
+			expected: []string{"P@55w0rd"},
+		},
+		{
+			scenario:      "Should return the selected variants",
+			normalization: Normalization{NFKC: true, Confusables: true},
+			password:      "Ｐаѕѕword",
+			expected:      []string{"Ｐаѕѕword", "Pаѕѕword", "Password"},
+		},
+		{
+			scenario:      "Should apply leet on top of the other variants",
+			normalization: Normalization{Confusables: true, Leet: true},
+			password:      "Р@ѕѕw0rd",
+			expected:      []string{"Р@ѕѕw0rd", "P@ssw0rd", "Рaѕѕword", "Password"},
+		},
+		{
+			scenario:      "Should not repeat variants",
+			normalization: Normalization{NFKC: true, Confusables: true, Leet: true},
+			password:      "password",
+			expected:      []string{"password"},
+		},
+	}
+
+	for _, test := range tests {
+		assertVariants(t, test.scenario, test.normalization.Variants(test.password), test.expected)
+	}
+}
+
+func assertVariants(t *testing.T, scenario string, variants, expected []string) {
+	t.Helper()
+
+	if len(variants) != len(expected) {
+		t.Errorf("Scenario '%s'. Expected: %q, Got: %q\n", scenario, expected, variants)
+		return
+	}
+	for i := range expected {
+		if variants[i] != expected[i] {
+			t.Errorf("Scenario '%s'. Expected: %q, Got: %q\n", scenario, expected, variants)
+			return
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package password
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -15,10 +16,14 @@ type (
 	}
 
 	Validations struct {
-		Case    validations.Case   `yaml:"case"`
-		Length  validations.Length `yaml:"length"`
-		Symbols validations.Symbol `yaml:"symbols"`
-		Numbers validations.Number `yaml:"numbers"`
+		Case       validations.Case       `yaml:"case"`
+		Length     validations.Length     `yaml:"length"`
+		Symbols    validations.Symbol     `yaml:"symbols"`
+		Numbers    validations.Number     `yaml:"numbers"`
+		Strength   validations.Strength   `yaml:"strength"`
+		Dictionary validations.Dictionary `yaml:"dictionary"`
+		Context    validations.Context    `yaml:"context"`
+		Pattern    validations.Pattern    `yaml:"pattern"`
 	}
 
 	password struct {
@@ -26,7 +31,7 @@ type (
 	}
 
 	Validator interface {
-		Validate(str string) (bool, error)
+		Validate(ctx context.Context, str string) (bool, error)
 	}
 
 	result struct {
@@ -41,15 +46,26 @@ func NewPasswordConfig(filePath string) *password {
 	if err != nil {
 		panic("Could not read configuration for password validations")
 	}
+	if err := passwordConfig.Validations.Dictionary.Load(); err != nil {
+		panic(fmt.Sprintf("Could not load dictionary for password validations: %s", err))
+	}
+	if err := passwordConfig.Validations.Pattern.Load(); err != nil {
+		panic(fmt.Sprintf("Could not load keyboard layouts for password validations: %s", err))
+	}
 
 	return &password{passwordConfig.Validations.ToList()}
 }
 
 func (p *Validations) ToList() []Validator {
-	return []Validator{&p.Case, &p.Length, &p.Symbols, &p.Numbers}
+	return []Validator{&p.Case, &p.Length, &p.Symbols, &p.Numbers, &p.Strength, &p.Dictionary, &p.Context, &p.Pattern}
 }
 
-func (p *password) Validate(password string) (bool, error) {
+// Validate runs every rule against the password, giving up once ctx is done.
+func (p *password) Validate(ctx context.Context, password string) (bool, error) {
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 
 	var waitGroup sync.WaitGroup
 	waitGroup.Add(len(p.validations))
@@ -61,7 +77,7 @@ func (p *password) Validate(password string) (bool, error) {
 
 		for _, validator := range p.validations {
 			go func(ruleValidator Validator) {
-				validatorResult <- <-validateWithRule(password, ruleValidator)
+				validatorResult <- <-validateWithRule(ctx, password, ruleValidator)
 				waitGroup.Done()
 			}(validator) // Send the current validator as a parameter, otherwise it always process the same
 		}
@@ -93,10 +109,10 @@ func (p *password) Validate(password string) (bool, error) {
 
 }
 
-func validateWithRule(password string, validator Validator) <-chan result {
+func validateWithRule(ctx context.Context, password string, validator Validator) <-chan result {
 	vr := make(chan result)
 	go func() {
-		ok, err := validator.Validate(password)
+		ok, err := validator.Validate(ctx, password)
 		vr <- result{ok, err}
 	}()
 
@@ -1,6 +1,7 @@
 package password
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -10,7 +11,7 @@ type testValidator struct {
 	err     error
 }
 
-func (f testValidator) Validate(str string) (bool, error) {
+func (f testValidator) Validate(ctx context.Context, str string) (bool, error) {
 	return f.isValid, f.err
 }
 
@@ -18,8 +19,8 @@ func TestToListShouldReturnAListOfValidators(t *testing.T) {
 
 	validations := &Validations{}
 	validators := validations.ToList()
-	if len(validators) != 4 {
-		t.Errorf("Expected validators %d, Got: %d\n", 4, len(validators))
+	if len(validators) != 8 {
+		t.Errorf("Expected validators %d, Got: %d\n", 8, len(validators))
 	}
 }
 
@@ -48,7 +49,7 @@ func TestValidateShouldReturnErrorIfAnyValidationReturnsError(t *testing.T) {
 	for _, test := range tests {
 
 		password := password{test.validators}
-		isValid, err := password.Validate("APassw0rd!")
+		isValid, err := password.Validate(context.Background(), "APassw0rd!")
 
 		if err == nil {
 			t.Errorf("Scenario: %s. Was expecting an error", test.scenario)
@@ -86,7 +87,7 @@ func TestValidateShouldSucceedIfAllValidationsSucceed(t *testing.T) {
 	for _, test := range tests {
 
 		password := password{test.validators}
-		isValid, err := password.Validate("APassw0rd!")
+		isValid, err := password.Validate(context.Background(), "APassw0rd!")
 
 		if err != nil {
 			t.Errorf("Scenario: %s. Wasn't expecting an error. Got : '%s'", test.scenario, err)
@@ -98,3 +99,19 @@ func TestValidateShouldSucceedIfAllValidationsSucceed(t *testing.T) {
 	}
 
 }
+
+func TestValidateShouldStopIfContextIsCancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	password := password{[]Validator{&testValidator{true, nil}}}
+	isValid, err := password.Validate(ctx, "APassw0rd!")
+
+	if err != context.Canceled {
+		t.Errorf("Was expecting a cancellation error. Got: '%v'", err)
+	}
+	if isValid {
+		t.Error("Was expecting 'isValid' to be false.")
+	}
+}
@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"fmt"
 	"unicode"
 )
@@ -13,7 +14,7 @@ type Case struct {
 	MinLower  int  `yaml:"minLower"`
 }
 
-func (cr *Case) Validate(password string) (bool, error) {
+func (cr *Case) Validate(ctx context.Context, password string) (bool, error) {
 
 	if cr.Enabled {
 
@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"testing"
 )
 
@@ -29,7 +30,7 @@ func TestCaseDisabled(t *testing.T) {
 	for _, test := range tests {
 
 		cv := &test.caseVal
-		ok, actualErr := cv.Validate(test.password)
+		ok, actualErr := cv.Validate(context.Background(), test.password)
 		if actualErr != test.expectedErr {
 			t.Errorf("Scenario: '%q'. Want: '%q' Got: '%q'\n", test.scenario, test.expectedErr, actualErr)
 		}
@@ -97,7 +98,7 @@ func TestValidateCaseShouldFail(t *testing.T) {
 
 		cv := &test.caseVal
 		for _, password := range test.passwords {
-			ok, actualErr := cv.Validate(password)
+			ok, actualErr := cv.Validate(context.Background(), password)
 			if actualErr == nil {
 				t.Errorf("Wanted an error for scenario: %q\n", test.scenario)
 			}
@@ -178,7 +179,7 @@ func TestValidateCaseShouldPass(t *testing.T) {
 
 		cv := &test.caseVal
 		for _, password := range test.passwords {
-			ok, actualErr := cv.Validate(password)
+			ok, actualErr := cv.Validate(context.Background(), password)
 			if actualErr != nil {
 				t.Errorf("Scenario '%q' Got error: '%q' for password: '%s'\n", test.scenario, actualErr, password)
 			}
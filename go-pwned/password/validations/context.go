@@ -0,0 +1,165 @@
+package validations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parts of the user details shorter than this are ignored when no minLength is
+// configured.
+const defaultMinContextLength = 3
+
+type (
+	// Context rejects passwords containing, backwards or not, the details of
+	// the user they are for, or within MaxEditDistance edits of them, as
+	// NIST SP 800-63B advises. The details are taken from the context with
+	// UserInfoFrom, and those shorter than MinLength are ignored.
+	Context struct {
+		Enabled         bool `yaml:"enabled"`
+		MinLength       int  `yaml:"minLength"`
+		MaxEditDistance int  `yaml:"maxEditDistance"`
+	}
+
+	// UserInfo are the details of the user a password is validated for. Any
+	// of them may be empty.
+	UserInfo struct {
+		Username    string
+		Email       string
+		DisplayName string
+		AppName     string
+	}
+
+	userInfoKey struct{}
+
+	// userDetail is a part of the user details passwords are compared with,
+	// and the name of the field it comes from.
+	userDetail struct {
+		field string
+		value string
+	}
+)
+
+// WithUserInfo returns a copy of ctx carrying the details of the user.
+func WithUserInfo(ctx context.Context, info UserInfo) context.Context {
+	return context.WithValue(ctx, userInfoKey{}, info)
+}
+
+// UserInfoFrom returns the details of the user carried by ctx, if any.
+func UserInfoFrom(ctx context.Context) (UserInfo, bool) {
+	info, ok := ctx.Value(userInfoKey{}).(UserInfo)
+	return info, ok
+}
+
+func (c *Context) Validate(ctx context.Context, password string) (bool, error) {
+
+	if c.Enabled {
+
+		info, ok := UserInfoFrom(ctx)
+		if !ok {
+			return true, nil
+		}
+
+		minLength := c.MinLength
+		if minLength < 1 {
+			minLength = defaultMinContextLength
+		}
+
+		lower := strings.ToLower(password)
+		for _, detail := range info.details() {
+			if utf8.RuneCountInString(detail.value) < minLength {
+				continue
+			}
+			if c.resembles(lower, detail.value) {
+				return false, fmt.Errorf("password should not contain or resemble the %s", detail.field)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// resembles tells whether password contains value, backwards or not, or is
+// within the edit distance of it. Both are expected in lower case.
+func (c *Context) resembles(password, value string) bool {
+
+	if strings.Contains(password, value) || strings.Contains(password, string(reverseRunes([]rune(value)))) {
+		return true
+	}
+	return c.MaxEditDistance > 0 && editDistance([]rune(password), []rune(value)) <= c.MaxEditDistance
+}
+
+// details lists the user details in lower case, along with the words they are
+// made of, as the local part of the email or every name of the display name.
+func (ui UserInfo) details() []userDetail {
+
+	var details []userDetail
+	add := func(field, value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return
+		}
+		details = append(details, userDetail{field, value})
+
+		words := strings.FieldsFunc(value, func(char rune) bool {
+			return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+		})
+		if len(words) > 1 {
+			details = append(details, userDetail{field, strings.Join(words, "")})
+			for _, word := range words {
+				details = append(details, userDetail{field, word})
+			}
+		}
+	}
+
+	add("username", ui.Username)
+	// Domains are shared by many users, so only the words of the local part
+	// are compared on their own
+	if email := strings.ToLower(strings.TrimSpace(ui.Email)); email != "" {
+		details = append(details, userDetail{"email", email})
+	}
+	if local, _, ok := strings.Cut(ui.Email, "@"); ok {
+		add("email", local)
+	}
+	add("display name", ui.DisplayName)
+	add("application name", ui.AppName)
+
+	return details
+}
+
+// editDistance is the Levenshtein distance between a and b, the fewest
+// insertions, deletions or replacements of characters turning a into b.
+func editDistance(a, b []rune) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
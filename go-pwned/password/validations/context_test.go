@@ -0,0 +1,154 @@
+package validations
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContextDisabled(t *testing.T) {
+
+	rule := &Context{Enabled: false}
+	ctx := WithUserInfo(context.Background(), UserInfo{Username: "jsmith"})
+
+	ok, err := rule.Validate(ctx, "jsmith2024!")
+	if err != nil {
+		t.Errorf("Context validator returned error: %q\n", err)
+	}
+	if ok != true {
+		t.Error("Was expecting ok to be true")
+	}
+}
+
+func TestContextValidation(t *testing.T) {
+
+	info := UserInfo{
+		Username:    "jsmith",
+		Email:       "john.smith@example.com",
+		DisplayName: "Jonathan Smith",
+		AppName:     "Billing",
+	}
+
+	tests := []struct {
+		scenario      string
+		rule          Context
+		info          *UserInfo
+		password      string
+		expectedValid bool
+		expectedError string
+	}{
+		{
+			scenario:      "Should reject passwords containing the username",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "JSmith#2024",
+			expectedError: "password should not contain or resemble the username",
+		},
+		{
+			scenario:      "Should reject passwords containing the username backwards",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "2024htimsj!",
+			expectedError: "password should not contain or resemble the username",
+		},
+		{
+			scenario:      "Should reject passwords containing the local part of the email",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "JohnSmith99!",
+			expectedError: "password should not contain or resemble the email",
+		},
+		{
+			scenario:      "Should reject passwords containing any name of the display name",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "MyJonathan1!",
+			expectedError: "password should not contain or resemble the display name",
+		},
+		{
+			scenario:      "Should reject passwords containing the application name",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "billing-2024",
+			expectedError: "password should not contain or resemble the application name",
+		},
+		{
+			scenario:      "Should reject passwords within the edit distance",
+			rule:          Context{Enabled: true, MaxEditDistance: 2},
+			info:          &UserInfo{Username: "jsmith"},
+			password:      "jsmiht",
+			expectedError: "password should not contain or resemble the username",
+		},
+		{
+			scenario:      "Should accept passwords beyond the edit distance",
+			rule:          Context{Enabled: true, MaxEditDistance: 1},
+			info:          &UserInfo{Username: "jsmith"},
+			password:      "jsmiht",
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should not compare the domain of the email",
+			rule:          Context{Enabled: true},
+			info:          &info,
+			password:      "Example.com1",
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should ignore details shorter than the minimum length",
+			rule:          Context{Enabled: true, MinLength: 5},
+			info:          &UserInfo{DisplayName: "Ann Lee"},
+			password:      "sleeping-ann",
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should accept passwords without user details",
+			rule:          Context{Enabled: true, MaxEditDistance: 2},
+			password:      "jsmith",
+			expectedValid: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		ctx := context.Background()
+		if test.info != nil {
+			ctx = WithUserInfo(ctx, *test.info)
+		}
+
+		ok, err := test.rule.Validate(ctx, test.password)
+		if ok != test.expectedValid {
+			t.Errorf("Scenario '%s'. Expected valid: %t, Got: %t\n", test.scenario, test.expectedValid, ok)
+		}
+		if test.expectedValid {
+			continue
+		}
+		if err == nil || err.Error() != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error '%s', Got: '%v'\n", test.scenario, test.expectedError, err)
+			continue
+		}
+		if strings.Contains(strings.ToLower(err.Error()), strings.ToLower(test.password)) {
+			t.Errorf("Scenario '%s'. Error should not contain the password, Got: '%s'\n", test.scenario, err)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"jsmith", "", 6},
+		{"jsmith", "jsmith", 0},
+		{"jsmith", "jsmyth", 1},
+		{"jsmith", "jsmiht", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, test := range tests {
+		if distance := editDistance([]rune(test.a), []rune(test.b)); distance != test.expected {
+			t.Errorf("Expected distance %d between '%s' and '%s', Got: %d\n", test.expected, test.a, test.b, distance)
+		}
+	}
+}
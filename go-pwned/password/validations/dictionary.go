@@ -0,0 +1,144 @@
+package validations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/normalize"
+)
+
+const (
+	// Words shorter than this are ignored when no minWordLength is configured.
+	defaultMinWordLength = 4
+	// Only this many characters of longer passwords are looked up, along with
+	// their variants, so the time taken doesn't grow with the password sent.
+	dictionaryMaxLength = normalize.MaxLength
+)
+
+type (
+	// Dictionary rejects passwords containing any of the words of its lists,
+	// whatever their case. Words shorter than MinWordLength are ignored, so
+	// short words don't ban most passwords. The lists are read by Load.
+	// Normalize also looks the words up in the selected variants of the
+	// password, as P@55w0rd for password.
+	Dictionary struct {
+		Enabled       bool                    `yaml:"enabled"`
+		MinWordLength int                     `yaml:"minWordLength"`
+		Lists         []DictionaryList        `yaml:"lists"`
+		Normalize     normalize.Normalization `yaml:"normalize"`
+
+		// Category of every word, in lower case
+		words map[string]string
+		// Length in characters of the longest word
+		maxLength int
+	}
+
+	// DictionaryList is a file of banned words, one per line. Empty lines and
+	// lines starting with # are skipped. Matches are reported by Category.
+	DictionaryList struct {
+		Category string `yaml:"category"`
+		Path     string `yaml:"path"`
+	}
+)
+
+// Load reads the words of every list, replacing those already read.
+func (d *Dictionary) Load() error {
+
+	if !d.Enabled {
+		return nil
+	}
+	if d.MinWordLength < 1 {
+		d.MinWordLength = defaultMinWordLength
+	}
+
+	d.words = make(map[string]string)
+	d.maxLength = 0
+	for _, list := range d.Lists {
+		if list.Category == "" {
+			return fmt.Errorf("dictionary list %s has no category", list.Path)
+		}
+		if err := d.loadList(list); err != nil {
+			return fmt.Errorf("could not load dictionary list %s: %w", list.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dictionary) loadList(list DictionaryList) error {
+
+	file, err := os.Open(list.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		length := utf8.RuneCountInString(word)
+		if length < d.MinWordLength || strings.HasPrefix(word, "#") {
+			continue
+		}
+
+		// Words in several lists are reported by the first one
+		if _, ok := d.words[word]; !ok {
+			d.words[word] = list.Category
+		}
+		if length > d.maxLength {
+			d.maxLength = length
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (d *Dictionary) Validate(ctx context.Context, password string) (bool, error) {
+
+	if d.Enabled {
+
+		categories := d.matchingCategories(password)
+		if len(categories) == 1 {
+			return false, fmt.Errorf("password contains a banned word from the '%s' list", escapePercentSymbol(categories[0]))
+		}
+		if len(categories) > 1 {
+			return false, fmt.Errorf("password contains banned words from the '%s' lists", escapePercentSymbol(strings.Join(categories, "', '")))
+		}
+	}
+
+	return true, nil
+}
+
+// matchingCategories returns the categories of the words found in password
+// or its variants, in the order of the lists.
+func (d *Dictionary) matchingCategories(password string) []string {
+
+	if runes := []rune(password); len(runes) > dictionaryMaxLength {
+		password = string(runes[:dictionaryMaxLength])
+	}
+
+	found := make(map[string]bool)
+	for _, variant := range d.Normalize.Variants(password) {
+		lower := []rune(strings.ToLower(variant))
+		for i := range lower {
+			for j := i + d.MinWordLength; j <= len(lower) && j-i <= d.maxLength; j++ {
+				if category, ok := d.words[string(lower[i:j])]; ok {
+					found[category] = true
+				}
+			}
+		}
+	}
+
+	var categories []string
+	for _, list := range d.Lists {
+		if found[list.Category] {
+			categories = append(categories, list.Category)
+			delete(found, list.Category)
+		}
+	}
+	return categories
+}
@@ -0,0 +1,202 @@
+package validations
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/normalize"
+)
+
+func writeWordList(t *testing.T, words ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(words, "\n")), 0o600); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	return path
+}
+
+func TestDictionaryDisabled(t *testing.T) {
+
+	dictionary := &Dictionary{Enabled: false, Lists: []DictionaryList{{Category: "company", Path: "missing.txt"}}}
+	if err := dictionary.Load(); err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	ok, err := dictionary.Validate(context.Background(), "Acme2024!")
+	if err != nil {
+		t.Errorf("Dictionary validator returned error: %q\n", err)
+	}
+	if ok != true {
+		t.Error("Was expecting ok to be true")
+	}
+}
+
+func TestDictionaryValidation(t *testing.T) {
+
+	dictionary := &Dictionary{
+		Enabled:       true,
+		MinWordLength: 4,
+		Lists: []DictionaryList{
+			{Category: "company", Path: writeWordList(t, "# Company names", "", "Acme", "AcmeCorp", "ac")},
+			{Category: "products", Path: writeWordList(t, "Rocket", "  anvil  ", "acme")},
+		},
+	}
+	if err := dictionary.Load(); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	tests := []struct {
+		scenario      string
+		password      string
+		expectedValid bool
+		expectedError string
+	}{
+		{
+			scenario:      "Should reject words whatever their case",
+			password:      "myACMEpass!",
+			expectedError: "password contains a banned word from the 'company' list",
+		},
+		{
+			scenario:      "Should trim words read from the lists",
+			password:      "Anvil-2024",
+			expectedError: "password contains a banned word from the 'products' list",
+		},
+		{
+			scenario:      "Should report every category found",
+			password:      "rocketacme",
+			expectedError: "password contains banned words from the 'company', 'products' lists",
+		},
+		{
+			scenario:      "Should ignore words shorter than the minimum length",
+			password:      "acrobat#123",
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should accept passwords without banned words",
+			password:      "Tr0ub4dour&3",
+			expectedValid: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		ok, err := dictionary.Validate(context.Background(), test.password)
+		if ok != test.expectedValid {
+			t.Errorf("Scenario '%s'. Expected valid: %t, Got: %t\n", test.scenario, test.expectedValid, ok)
+		}
+		if test.expectedValid {
+			continue
+		}
+		if err == nil || err.Error() != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error '%s', Got: '%v'\n", test.scenario, test.expectedError, err)
+			continue
+		}
+		if strings.Contains(err.Error(), test.password) {
+			t.Errorf("Scenario '%s'. Error should not contain the password, Got: '%s'\n", test.scenario, err)
+		}
+	}
+}
+
+func TestDictionaryLoadShouldFail(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		list     DictionaryList
+	}{
+		{
+			scenario: "Should fail if a list can't be read",
+			list:     DictionaryList{Category: "company", Path: filepath.Join(t.TempDir(), "missing.txt")},
+		},
+		{
+			scenario: "Should fail if a list has no category",
+			list:     DictionaryList{Path: writeWordList(t, "acme")},
+		},
+	}
+
+	for _, test := range tests {
+		dictionary := &Dictionary{Enabled: true, Lists: []DictionaryList{test.list}}
+		if err := dictionary.Load(); err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+		}
+	}
+}
+
+func TestDictionaryValidationShouldNormalize(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		normalize     normalize.Normalization
+		password      string
+		expectedValid bool
+	}{
+		{
+			scenario:      "Should not find words spelled in leet without normalization",
+			password:      "4cm3Corp!",
+			expectedValid: true,
+		},
+		{
+			scenario:  "Should find words spelled in leet",
+			normalize: normalize.Normalization{Leet: true},
+			password:  "4cm3Corp!",
+		},
+		{
+			scenario:  "Should find words written with look-alikes",
+			normalize: normalize.Normalization{Confusables: true},
+			password:  "Асmе-2024",
+		},
+		{
+			scenario:  "Should find words written in fullwidth",
+			normalize: normalize.Normalization{NFKC: true},
+			password:  "ＡＣＭＥ2024",
+		},
+	}
+
+	for _, test := range tests {
+
+		dictionary := &Dictionary{
+			Enabled:   true,
+			Lists:     []DictionaryList{{Category: "company", Path: writeWordList(t, "acme")}},
+			Normalize: test.normalize,
+		}
+		if err := dictionary.Load(); err != nil {
+			t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+		}
+
+		if ok, err := dictionary.Validate(context.Background(), test.password); ok != test.expectedValid {
+			t.Errorf("Scenario '%s'. Expected valid: %t, Got: %t (%v)\n", test.scenario, test.expectedValid, ok, err)
+		}
+	}
+}
+
+func TestDictionaryValidationShouldOnlyLookUpMaxLength(t *testing.T) {
+
+	dictionary := &Dictionary{
+		Enabled:   true,
+		Lists:     []DictionaryList{{Category: "company", Path: writeWordList(t, "acme")}},
+		Normalize: normalize.Normalization{NFKC: true, Confusables: true, Leet: true},
+	}
+	if err := dictionary.Load(); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	padding := strings.Repeat("x1", dictionaryMaxLength/2)
+	tests := []struct {
+		scenario      string
+		password      string
+		expectedValid bool
+	}{
+		{"Should find words within the length looked up", "@cme" + padding, false},
+		{"Should ignore words beyond the length looked up", padding + "@cme", true},
+	}
+
+	for _, test := range tests {
+		if ok, _ := dictionary.Validate(context.Background(), test.password); ok != test.expectedValid {
+			t.Errorf("Scenario '%s'. Expected valid: %t, Got: %t\n", test.scenario, test.expectedValid, ok)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package validations
+
+import "strings"
+
+type (
+	// keyboardLayout describes a keyboard as rows of keys separated by spaces,
+	// each key listing its unshifted and shifted characters. offsets holds the
+	// column of the first key of every row. On slanted keyboards every row is
+	// shifted right from the one above it, so a key touches two keys of the row
+	// above and two of the row below instead of three.
+	keyboardLayout struct {
+		rows    []string
+		offsets []int
+		slanted bool
+	}
+
+	// keyboardGraph lists, for every character of a layout, the keys around
+	// it in a fixed order of directions, so changes of direction can be told
+	// apart. Directions without key hold an empty string.
+	keyboardGraph struct {
+		name          string
+		adjacent      map[rune][]string
+		shifted       map[rune]bool
+		averageDegree float64
+	}
+
+	// keyboardWalk is a run of characters typed on adjacent keys, from i to j
+	// inclusive.
+	keyboardWalk struct {
+		i, j    int
+		turns   int
+		shifted int
+		graph   *keyboardGraph
+	}
+)
+
+var (
+	keyboardLayouts = map[string]keyboardLayout{
+		"qwerty": {
+			rows: []string{
+				"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+				"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+				"aA sS dD fF gG hH jJ kK lL ;: '\"",
+				"zZ xX cC vV bB nN mM ,< .> /?",
+			},
+			offsets: []int{0, 1, 1, 1},
+			slanted: true,
+		},
+		"dvorak": {
+			rows: []string{
+				"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) [{ ]}",
+				"'\" ,< .> pP yY fF gG cC rR lL /? =+ \\|",
+				"aA oO eE uU iI dD hH tT nN sS -_",
+				";: qQ jJ kK xX bB mM wW vV zZ",
+			},
+			offsets: []int{0, 1, 1, 1},
+			slanted: true,
+		},
+		"azerty": {
+			rows: []string{
+				"²³ &1 é2 \"3 '4 (5 -6 è7 _8 ç9 à0 )° =+",
+				"aA zZ eE rR tT yY uU iI oO pP ^¨ $£",
+				"qQ sS dD fF gG hH jJ kK lL mM ù% *µ",
+				"<> wW xX cC vV bB nN ,? ;. :/ !§",
+			},
+			offsets: []int{0, 1, 1, 0},
+			slanted: true,
+		},
+		"keypad": {
+			rows: []string{
+				"/ * -",
+				"7 8 9 +",
+				"4 5 6",
+				"1 2 3",
+				"0 .",
+			},
+			offsets: []int{1, 0, 0, 0, 1},
+		},
+	}
+
+	keyboardGraphs = buildKeyboardGraphs(keyboardLayouts)
+)
+
+func buildKeyboardGraphs(layouts map[string]keyboardLayout) map[string]*keyboardGraph {
+
+	graphs := make(map[string]*keyboardGraph, len(layouts))
+	for name, layout := range layouts {
+		graphs[name] = newKeyboardGraph(name, layout)
+	}
+	return graphs
+}
+
+func newKeyboardGraph(name string, layout keyboardLayout) *keyboardGraph {
+
+	type position struct{ x, y int }
+
+	keys := make(map[position]string)
+	for y, row := range layout.rows {
+		for x, key := range strings.Fields(row) {
+			keys[position{x + layout.offsets[y], y}] = key
+		}
+	}
+
+	directions := []position{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	if layout.slanted {
+		directions = []position{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+	}
+
+	graph := &keyboardGraph{name: name, adjacent: make(map[rune][]string), shifted: make(map[rune]bool)}
+	for at, key := range keys {
+		neighbours := make([]string, len(directions))
+		for i, direction := range directions {
+			neighbours[i] = keys[position{at.x + direction.x, at.y + direction.y}]
+		}
+
+		// Every character of a key has the same neighbours
+		for i, char := range []rune(key) {
+			graph.adjacent[char] = neighbours
+			graph.shifted[char] = i > 0
+		}
+	}
+
+	degrees := 0
+	for _, neighbours := range graph.adjacent {
+		degrees += countKeys(neighbours)
+	}
+	graph.averageDegree = float64(degrees) / float64(len(graph.adjacent))
+
+	return graph
+}
+
+func countKeys(neighbours []string) (total int) {
+	for _, neighbour := range neighbours {
+		if neighbour != "" {
+			total++
+		}
+	}
+	return
+}
+
+// startingPositions is the number of characters a walk can start from.
+func (kg *keyboardGraph) startingPositions() int {
+	return len(kg.adjacent)
+}
+
+// walks finds the runs of at least minLength characters typed on adjacent
+// keys, whether shifted or not.
+func (kg *keyboardGraph) walks(password []rune, minLength int) []keyboardWalk {
+
+	var walks []keyboardWalk
+	for i := 0; i < len(password)-1; {
+
+		walk := keyboardWalk{i: i, graph: kg}
+		if kg.shifted[password[i]] {
+			walk.shifted++
+		}
+
+		lastDirection := -1
+		j := i + 1
+		for ; j < len(password); j++ {
+			direction, shifted := kg.direction(password[j-1], password[j])
+			if direction < 0 {
+				break
+			}
+			if shifted {
+				walk.shifted++
+			}
+			if direction != lastDirection {
+				walk.turns++
+				lastDirection = direction
+			}
+		}
+
+		if j-i >= minLength {
+			walk.j = j - 1
+			walks = append(walks, walk)
+		}
+		i = j
+	}
+
+	return walks
+}
+
+// direction returns the direction to move from key to next, or -1 if they
+// aren't adjacent, and whether next is typed shifted.
+func (kg *keyboardGraph) direction(from, to rune) (int, bool) {
+
+	for direction, neighbour := range kg.adjacent[from] {
+		for i, char := range []rune(neighbour) {
+			if char == to {
+				return direction, i > 0
+			}
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,79 @@
+package validations
+
+import "testing"
+
+func TestKeyboardWalks(t *testing.T) {
+
+	tests := []struct {
+		scenario        string
+		layout          string
+		password        string
+		expectedWalks   int
+		expectedTurns   int
+		expectedShifted int
+	}{
+		{
+			scenario:      "Should find a straight walk",
+			layout:        "qwerty",
+			password:      "qwerty",
+			expectedWalks: 1,
+			expectedTurns: 1,
+		},
+		{
+			scenario:      "Should count the turns of a walk",
+			layout:        "qwerty",
+			password:      "zxcvfdsa",
+			expectedWalks: 1,
+			expectedTurns: 3,
+		},
+		{
+			scenario:        "Should count the shifted keys of a walk",
+			layout:          "qwerty",
+			password:        "!@#$",
+			expectedWalks:   1,
+			expectedTurns:   1,
+			expectedShifted: 4,
+		},
+		{
+			scenario:      "Should find walks on other layouts",
+			layout:        "dvorak",
+			password:      "aoeu",
+			expectedWalks: 1,
+			expectedTurns: 1,
+		},
+		{
+			scenario:      "Should find walks on the keypad",
+			layout:        "keypad",
+			password:      "7896",
+			expectedWalks: 1,
+			expectedTurns: 2,
+		},
+		{
+			scenario:      "Should ignore walks shorter than the minimum",
+			layout:        "qwerty",
+			password:      "qwPzx",
+			expectedWalks: 0,
+		},
+		{
+			scenario:      "Should not take repeated keys as a walk",
+			layout:        "qwerty",
+			password:      "aaaa",
+			expectedWalks: 0,
+		},
+	}
+
+	for _, test := range tests {
+
+		walks := keyboardGraphs[test.layout].walks([]rune(test.password), 3)
+		if len(walks) != test.expectedWalks {
+			t.Errorf("Scenario '%s'. Expected %d walks, Got: %d\n", test.scenario, test.expectedWalks, len(walks))
+			continue
+		}
+		if len(walks) == 0 {
+			continue
+		}
+		if walks[0].turns != test.expectedTurns || walks[0].shifted != test.expectedShifted {
+			t.Errorf("Scenario '%s'. Expected %d turns and %d shifted keys, Got: %d and %d\n", test.scenario, test.expectedTurns, test.expectedShifted, walks[0].turns, walks[0].shifted)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"fmt"
 	"unicode/utf8"
 )
@@ -11,7 +12,7 @@ type Length struct {
 	Max     int  `yaml:"max"`
 }
 
-func (lr *Length) Validate(password string) (bool, error) {
+func (lr *Length) Validate(ctx context.Context, password string) (bool, error) {
 
 	if lr.Enabled {
 
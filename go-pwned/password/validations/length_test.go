@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"testing"
 )
 
@@ -12,7 +13,7 @@ func TestValidateLengthDisabled(t *testing.T) {
 		Max:     0,
 	}
 
-	ok, result := lengthRule.Validate("passw0rd")
+	ok, result := lengthRule.Validate(context.Background(), "passw0rd")
 
 	if result != nil {
 		t.Errorf("Length validator returned error: %q\n", result)
@@ -53,7 +54,7 @@ func TestValidateLengthShouldFail(t *testing.T) {
 	for _, test := range tests {
 		lr := test.lengthRule
 		for _, password := range test.passwords {
-			ok, expected := lr.Validate(password)
+			ok, expected := lr.Validate(context.Background(), password)
 			if expected == nil {
 				t.Errorf("Expected error for scenario %s\n", test.scenario)
 			}
@@ -96,7 +97,7 @@ func TestValidateLengthShouldPass(t *testing.T) {
 	for _, test := range tests {
 		length := test.lengthRule
 		for _, password := range test.passwords {
-			ok, error := length.Validate(password)
+			ok, error := length.Validate(context.Background(), password)
 			if error != nil {
 				t.Errorf("Got unexpected error %s for scenario %s\n", error.Error(), test.scenario)
 			}
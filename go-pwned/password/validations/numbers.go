@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"fmt"
 	"unicode"
 	"unicode/utf8"
@@ -13,7 +14,7 @@ type Number struct {
 	OnlyNumbers  bool `yaml:"onlyNumbers"`
 }
 
-func (nr *Number) Validate(password string) (bool, error) {
+func (nr *Number) Validate(ctx context.Context, password string) (bool, error) {
 
 	if nr.Enabled {
 		totalNumbers := countNumbers(password)
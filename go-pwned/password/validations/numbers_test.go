@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"testing"
 )
 
@@ -13,7 +14,7 @@ func TestDisabled(t *testing.T) {
 		OnlyNumbers:  false,
 	}
 
-	ok, result := numberRules.Validate("passw0rd")
+	ok, result := numberRules.Validate(context.Background(), "passw0rd")
 
 	if result != nil {
 		t.Errorf("Number validator returned error: %q\n", result)
@@ -66,7 +67,7 @@ func TestNumberValiationShouldFail(t *testing.T) {
 	for _, test := range tests {
 		lr := test.numberRules
 		for _, password := range test.passwords {
-			ok, expected := lr.Validate(password)
+			ok, expected := lr.Validate(context.Background(), password)
 			if expected == nil {
 				t.Errorf("Expected error for scenario %s\n", test.scenario)
 			}
@@ -120,7 +121,7 @@ func TestValidationShouldPass(t *testing.T) {
 	for _, test := range tests {
 		lr := test.numberRules
 		for _, password := range test.passwords {
-			ok, expected := lr.Validate(password)
+			ok, expected := lr.Validate(context.Background(), password)
 			if expected != nil {
 				t.Errorf("Got unexpected error for scenario '%s'. Error is: %s\n", test.scenario, expected)
 			}
@@ -0,0 +1,155 @@
+package validations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Keyboard walks are looked for on this layout when none is configured.
+const defaultKeyboardLayout = "qwerty"
+
+type (
+	// Pattern rejects passwords with more than MaxRepeats identical
+	// consecutive characters, ascending or descending runs of more than
+	// MaxSequence letters or digits, as abcd or 4321, or more than
+	// MaxKeyboardWalk adjacent keys typed in a row, as qwerty or asdf, on any
+	// of KeyboardLayouts. Limits set to 0 are not checked. The layouts are
+	// resolved by Load.
+	Pattern struct {
+		Enabled         bool     `yaml:"enabled"`
+		MaxRepeats      int      `yaml:"maxRepeats"`
+		MaxSequence     int      `yaml:"maxSequence"`
+		MaxKeyboardWalk int      `yaml:"maxKeyboardWalk"`
+		KeyboardLayouts []string `yaml:"keyboardLayouts"`
+
+		graphs []*keyboardGraph
+	}
+)
+
+// Load resolves the keyboard layouts, failing for unknown ones.
+func (p *Pattern) Load() error {
+
+	if !p.Enabled {
+		return nil
+	}
+
+	layouts := p.KeyboardLayouts
+	if len(layouts) == 0 {
+		layouts = []string{defaultKeyboardLayout}
+	}
+
+	p.graphs = make([]*keyboardGraph, 0, len(layouts))
+	for _, layout := range layouts {
+		graph, ok := keyboardGraphs[strings.ToLower(layout)]
+		if !ok {
+			return fmt.Errorf("unknown keyboard layout '%s', expected one of %s", layout, strings.Join(keyboardLayoutNames(), ", "))
+		}
+		p.graphs = append(p.graphs, graph)
+	}
+
+	return nil
+}
+
+func (p *Pattern) Validate(ctx context.Context, password string) (bool, error) {
+
+	if p.Enabled {
+
+		runes := []rune(password)
+
+		if p.MaxRepeats > 0 && longestRepeat(runes) > p.MaxRepeats {
+			return false, fmt.Errorf("password contains more than %d identical consecutive characters", p.MaxRepeats)
+		}
+
+		if p.MaxSequence > 0 && longestSequence(runes) > p.MaxSequence {
+			return false, fmt.Errorf("password contains a sequence of more than %d characters", p.MaxSequence)
+		}
+
+		if p.MaxKeyboardWalk > 0 {
+			for _, graph := range p.graphs {
+				if len(graph.walks(runes, p.MaxKeyboardWalk+1)) > 0 {
+					return false, fmt.Errorf("password contains a keyboard walk of more than %d keys", p.MaxKeyboardWalk)
+				}
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// longestRepeat is the length of the longest run of identical characters.
+func longestRepeat(password []rune) (longest int) {
+
+	for i := 0; i < len(password); {
+		j := i + 1
+		for j < len(password) && password[j] == password[i] {
+			j++
+		}
+		if j-i > longest {
+			longest = j - i
+		}
+		i = j
+	}
+
+	return
+}
+
+// longestSequence is the length of the longest run of letters or digits each
+// following the previous one, forwards or backwards, whatever their case. Runs
+// are at least two characters long, so it is 0 if there is none.
+func longestSequence(password []rune) (longest int) {
+
+	for i := 0; i+1 < len(password); {
+
+		step := sequenceStep(password[i], password[i+1])
+		if step == 0 {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j+1 < len(password) && sequenceStep(password[j], password[j+1]) == step {
+			j++
+		}
+		if j-i+1 > longest {
+			longest = j - i + 1
+		}
+		// Runs within this one are shorter, but the next may start at its
+		// end, as abcba
+		i = j
+	}
+
+	return
+}
+
+// sequenceStep returns 1 if next follows char, -1 if it precedes it, and 0
+// otherwise or if they aren't both letters or both digits.
+func sequenceStep(char, next rune) int {
+
+	char, next = unicode.ToLower(char), unicode.ToLower(next)
+	bothLetters := unicode.IsLetter(char) && unicode.IsLetter(next)
+	bothDigits := unicode.IsDigit(char) && unicode.IsDigit(next)
+	if !bothLetters && !bothDigits {
+		return 0
+	}
+
+	switch next - char {
+	case 1:
+		return 1
+	case -1:
+		return -1
+	}
+	return 0
+}
+
+func keyboardLayoutNames() []string {
+
+	names := make([]string, 0, len(keyboardLayouts))
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,129 @@
+package validations
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPatternDisabled(t *testing.T) {
+
+	pattern := &Pattern{Enabled: false, MaxRepeats: 1, MaxSequence: 1, MaxKeyboardWalk: 1}
+	if err := pattern.Load(); err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	ok, err := pattern.Validate(context.Background(), "aaaa1234qwerty")
+	if err != nil {
+		t.Errorf("Pattern validator returned error: %q\n", err)
+	}
+	if ok != true {
+		t.Error("Was expecting ok to be true")
+	}
+}
+
+func TestPatternValidation(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		pattern       Pattern
+		passwords     []string
+		expectedValid bool
+		expectedError string
+	}{
+		{
+			scenario:      "Should reject too many identical consecutive characters",
+			pattern:       Pattern{Enabled: true, MaxRepeats: 2},
+			passwords:     []string{"Paaassword", "Pass111word", "P!!!word"},
+			expectedError: "password contains more than 2 identical consecutive characters",
+		},
+		{
+			scenario:      "Should accept identical characters up to the limit",
+			pattern:       Pattern{Enabled: true, MaxRepeats: 2},
+			passwords:     []string{"Passw00rd", "aAaA"},
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should reject ascending and descending runs",
+			pattern:       Pattern{Enabled: true, MaxSequence: 3},
+			passwords:     []string{"xabcdx", "pass4321", "ZyXw!"},
+			expectedError: "password contains a sequence of more than 3 characters",
+		},
+		{
+			scenario:      "Should accept runs up to the limit",
+			pattern:       Pattern{Enabled: true, MaxSequence: 3},
+			passwords:     []string{"abc-dcb", "123a4", "9:;<"},
+			expectedValid: true,
+		},
+		{
+			scenario:      "Should reject keyboard walks on the default layout",
+			pattern:       Pattern{Enabled: true, MaxKeyboardWalk: 3},
+			passwords:     []string{"myqwerty", "asdf!", "ZXCV", "1qaz"},
+			expectedError: "password contains a keyboard walk of more than 3 keys",
+		},
+		{
+			scenario:      "Should reject keyboard walks on every configured layout",
+			pattern:       Pattern{Enabled: true, MaxKeyboardWalk: 3, KeyboardLayouts: []string{"qwerty", "Dvorak"}},
+			passwords:     []string{"aoeu-x", "qwer-x"},
+			expectedError: "password contains a keyboard walk of more than 3 keys",
+		},
+		{
+			scenario:      "Should accept keyboard walks up to the limit",
+			pattern:       Pattern{Enabled: true, MaxKeyboardWalk: 3},
+			passwords:     []string{"qwe-asd", "aoeu-x"},
+			expectedValid: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		pattern := test.pattern
+		if err := pattern.Load(); err != nil {
+			t.Fatalf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+
+		for _, password := range test.passwords {
+			ok, err := pattern.Validate(context.Background(), password)
+			if ok != test.expectedValid {
+				t.Errorf("Scenario '%s'. Expected valid: %t for '%s', Got: %t\n", test.scenario, test.expectedValid, password, ok)
+			}
+			if !test.expectedValid && (err == nil || err.Error() != test.expectedError) {
+				t.Errorf("Scenario '%s'. Expected error '%s' for '%s', Got: '%v'\n", test.scenario, test.expectedError, password, err)
+			}
+		}
+	}
+}
+
+func TestPatternLoadShouldRejectUnknownLayouts(t *testing.T) {
+
+	pattern := &Pattern{Enabled: true, MaxKeyboardWalk: 3, KeyboardLayouts: []string{"colemak"}}
+	if err := pattern.Load(); err == nil {
+		t.Error("Was expecting an error")
+	}
+}
+
+func TestLongestSequence(t *testing.T) {
+
+	// Unicode letter ranges are much longer than the latin alphabet
+	cjk := make([]rune, 20000)
+	for i := range cjk {
+		cjk[i] = 0x4E00 + rune(i)
+	}
+
+	tests := []struct {
+		scenario string
+		password []rune
+		expected int
+	}{
+		{"Should find no run without consecutive characters", []rune("a-b-c"), 0},
+		{"Should find runs sharing an end", []rune("abcba"), 3},
+		{"Should find the longest of several runs", []rune("ab-wxyz-321"), 4},
+		{"Should find long Unicode runs", cjk, len(cjk)},
+		{"Should find long descending Unicode runs", reverseRunes(cjk), len(cjk)},
+	}
+
+	for _, test := range tests {
+		if longest := longestSequence(test.password); longest != test.expected {
+			t.Errorf("Scenario '%s'. Expected %d, Got: %d\n", test.scenario, test.expected, longest)
+		}
+	}
+}
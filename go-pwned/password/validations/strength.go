@@ -0,0 +1,362 @@
+package validations
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	// Guesses per second of an offline attack against a slow hash, as used to
+	// tell how long a password would take to crack.
+	strengthGuessesPerSecond = 1e4
+	// Passwords are only analysed up to this length, bounding the time taken
+	// by the estimate, which grows with its square. The rest of longer
+	// passwords is ignored, as it could be a repetition of the analysed part.
+	strengthMaxLength = 100
+	// Guesses added to every pattern a password is made of beyond the first,
+	// so simple passwords are preferred over long lists of patterns.
+	strengthMinGuessesPerPattern = 1e4
+)
+
+type (
+	// Strength rejects passwords which are easy to guess, estimating the
+	// guesses needed to find them out of the common words, sequences,
+	// repetitions, dates and keyboard walks they are made of. The estimate is
+	// turned into a score from 0, too guessable, to 4, very unguessable, and
+	// passwords scoring below MinScore are rejected.
+	Strength struct {
+		Enabled  bool `yaml:"enabled"`
+		MinScore int  `yaml:"minScore"`
+	}
+
+	// Estimation is how guessable a password is.
+	Estimation struct {
+		Guesses          float64
+		Score            int
+		CrackTimeSeconds float64
+		// Patterns lists the types of the patterns the password is made of,
+		// without the characters matching them.
+		Patterns []string
+	}
+
+	// StrengthError is returned by Strength for passwords scoring too low.
+	StrengthError struct {
+		Estimation
+		MinScore int
+	}
+
+	// StrengthReport receives the estimation of the password Strength
+	// validates with a context carrying it, so it can be returned along with
+	// the result of the validation.
+	StrengthReport struct {
+		mutex      sync.Mutex
+		estimation *Estimation
+	}
+
+	strengthReportKey struct{}
+)
+
+// WithStrengthReport returns a copy of ctx carrying report.
+func WithStrengthReport(ctx context.Context, report *StrengthReport) context.Context {
+	return context.WithValue(ctx, strengthReportKey{}, report)
+}
+
+// StrengthReportFrom returns the report carried by ctx, if any.
+func StrengthReportFrom(ctx context.Context) (*StrengthReport, bool) {
+	report, ok := ctx.Value(strengthReportKey{}).(*StrengthReport)
+	return report, ok
+}
+
+func (s *Strength) Validate(ctx context.Context, password string) (bool, error) {
+
+	if s.Enabled {
+
+		estimation := Estimate(password)
+		if report, ok := StrengthReportFrom(ctx); ok {
+			report.record(estimation)
+		}
+		if estimation.Score < s.MinScore {
+			return false, &StrengthError{estimation, s.MinScore}
+		}
+	}
+
+	return true, nil
+}
+
+// Estimation returns the estimation received, if the password was estimated.
+func (sr *StrengthReport) Estimation() (Estimation, bool) {
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.estimation == nil {
+		return Estimation{}, false
+	}
+	return *sr.estimation, true
+}
+
+func (sr *StrengthReport) record(estimation Estimation) {
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.estimation = &estimation
+}
+
+func (se *StrengthError) Error() string {
+
+	message := fmt.Sprintf("password is too easy to guess, it scores %d of 4 and could be cracked in %s", se.Score, se.CrackTime())
+	if found := se.describePatterns(); found != "" {
+		message += " (" + found + ")"
+	}
+	return fmt.Sprintf("%s, it should score at least %d", message, se.MinScore)
+}
+
+func (se *StrengthError) describePatterns() string {
+
+	descriptions := map[string]string{
+		dictionaryPattern: "common word",
+		sequencePattern:   "sequence",
+		repeatPattern:     "repetition",
+		datePattern:       "date",
+		keyboardPattern:   "keyboard walk",
+	}
+
+	var found []string
+	for _, pattern := range se.Patterns {
+		if description, ok := descriptions[pattern]; ok {
+			found = append(found, description)
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	return "contains " + strings.Join(found, ", ")
+}
+
+// Estimate finds out the easiest way to guess password, as the sequence of
+// patterns covering it which takes the fewest guesses.
+func Estimate(password string) Estimation {
+
+	runes := []rune(password)
+	if len(runes) > strengthMaxLength {
+		runes = runes[:strengthMaxLength]
+	}
+
+	guesses, sequence := mostGuessableSequence(runes)
+
+	estimation := Estimation{
+		Guesses:          guesses,
+		Score:            strengthScore(guesses),
+		CrackTimeSeconds: guesses / strengthGuessesPerSecond,
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range sequence {
+		if match.pattern != bruteforcePattern && !seen[match.pattern] {
+			seen[match.pattern] = true
+			estimation.Patterns = append(estimation.Patterns, match.pattern)
+		}
+	}
+
+	return estimation
+}
+
+// CrackTime describes CrackTimeSeconds in the largest unit that fits it.
+func (e Estimation) CrackTime() string {
+
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"century", 100 * 365 * 24 * 3600},
+		{"year", 365 * 24 * 3600},
+		{"month", 31 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	seconds := e.CrackTimeSeconds
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= 100*units[0].seconds {
+		return "centuries"
+	}
+
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			amount := int(math.Round(seconds / unit.seconds))
+			if amount == 1 {
+				return "1 " + unit.name
+			}
+			if unit.name == "century" {
+				return fmt.Sprintf("%d centuries", amount)
+			}
+			return fmt.Sprintf("%d %ss", amount, unit.name)
+		}
+	}
+	return "less than a second"
+}
+
+func strengthScore(guesses float64) int {
+
+	thresholds := []float64{1e3, 1e6, 1e8, 1e10}
+	for score, threshold := range thresholds {
+		// Passwords just above a threshold are still scored below it
+		if guesses < threshold+5 {
+			return score
+		}
+	}
+	return len(thresholds)
+}
+
+// mostGuessableSequence returns the fewest guesses needed to find password,
+// out of every sequence of non-overlapping matches covering it with the gaps
+// brute forced, and that sequence.
+//
+// A sequence of l matches needs l! times the product of their guesses, as
+// patterns can come in any order, plus the guesses spent on shorter
+// sequences, at least strengthMinGuessesPerPattern^(l-1). Brute forcing the
+// whole password takes 10^n guesses, so sequences of more than n/4 + 1
+// matches are never the best and aren't tried. The best sequence of every
+// other length ending at every position is kept, and there are a few matches
+// per character, so the search is quadratic on the length of the password.
+func mostGuessableSequence(password []rune) (float64, []strengthMatch) {
+
+	n := len(password)
+	if n == 0 {
+		return 1, nil
+	}
+
+	matchesByEnd := make([][]strengthMatch, n)
+	for _, match := range findStrengthMatches(password) {
+		matchesByEnd[match.j] = append(matchesByEnd[match.j], match)
+	}
+
+	maxLength := (n-1)/4 + 1
+
+	// best[k][l] is the best sequence of l matches covering password[:k+1],
+	// if its match is set
+	type candidate struct {
+		match   strengthMatch
+		product float64
+		guesses float64
+	}
+	best := make([][]candidate, n)
+	for k := range best {
+		best[k] = make([]candidate, maxLength+1)
+	}
+
+	// update extends the product of the guesses of a sequence of length-1
+	// matches with match.
+	update := func(match strengthMatch, length int, product float64) {
+
+		if length > maxLength {
+			return
+		}
+		product *= minimumGuesses(match, n)
+		guesses := factorial(length)*product + math.Pow(strengthMinGuessesPerPattern, float64(length-1))
+		if current := best[match.j][length]; current.match.pattern == "" || guesses < current.guesses {
+			best[match.j][length] = candidate{match, product, guesses}
+		}
+	}
+
+	// gaps[l] is where the cheapest brute force of at least two characters
+	// ending at the current position starts, after a sequence of l-1 matches
+	// not ending in a brute force, and the product of their guesses. Every
+	// character added multiplies all of them by 10, so it stays the cheapest.
+	type gap struct {
+		i       int
+		product float64
+	}
+	gaps := make([]gap, maxLength+1)
+
+	for k := 0; k < n; k++ {
+
+		for _, match := range matchesByEnd[k] {
+			if match.i == 0 {
+				update(match, 1, 1)
+				continue
+			}
+			for length, previous := range best[match.i-1] {
+				if previous.match.pattern != "" {
+					update(match, length+1, previous.product)
+				}
+			}
+		}
+
+		// Brute force password[i:k+1], unless it follows another brute force
+		update(bruteforceMatch(password, 0, k), 1, 1)
+		if k == 0 {
+			continue
+		}
+		for length, previous := range best[k-1] {
+			if previous.match.pattern != "" && previous.match.pattern != bruteforcePattern {
+				update(bruteforceMatch(password, k, k), length+1, previous.product)
+			}
+		}
+		if k == 1 {
+			continue
+		}
+		for length, previous := range best[k-2] {
+			if previous.match.pattern == "" || previous.match.pattern == bruteforcePattern || length+1 > maxLength {
+				continue
+			}
+			// A gap starting one character later takes 10 times fewer guesses
+			current := gaps[length+1]
+			if current.product == 0 || previous.product < current.product*math.Pow(10, float64(k-1-current.i)) {
+				gaps[length+1] = gap{k - 1, previous.product}
+			}
+		}
+		for length, gap := range gaps {
+			if gap.product != 0 {
+				update(bruteforceMatch(password, gap.i, k), length, gap.product)
+			}
+		}
+	}
+
+	bestLength, guesses := 0, math.Inf(1)
+	for length, candidate := range best[n-1] {
+		if candidate.match.pattern != "" && candidate.guesses < guesses {
+			bestLength, guesses = length, candidate.guesses
+		}
+	}
+
+	sequence := make([]strengthMatch, bestLength)
+	for k, length := n-1, bestLength; k >= 0; length-- {
+		match := best[k][length].match
+		sequence[length-1] = match
+		k = match.i - 1
+	}
+
+	return guesses, sequence
+}
+
+// minimumGuesses raises the guesses of matches which only cover part of the
+// password, as an attacker can't know they are there.
+func minimumGuesses(match strengthMatch, passwordLength int) float64 {
+
+	guesses := match.guesses
+	if match.j-match.i+1 < passwordLength {
+		minimum := 50.0
+		if match.i == match.j {
+			minimum = 10
+		}
+		guesses = math.Max(guesses, minimum)
+	}
+	return guesses
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
@@ -0,0 +1,490 @@
+package validations
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/normalize"
+)
+
+const (
+	dictionaryPattern = "dictionary"
+	sequencePattern   = "sequence"
+	repeatPattern     = "repeat"
+	datePattern       = "date"
+	keyboardPattern   = "keyboard"
+	bruteforcePattern = "bruteforce"
+)
+
+type (
+	// strengthMatch is a part of a password, from i to j inclusive, following
+	// a pattern which takes guesses to guess.
+	strengthMatch struct {
+		pattern string
+		i, j    int
+		guesses float64
+	}
+)
+
+var (
+	// Years dates are compared with, the further the harder to guess.
+	referenceYear = time.Now().Year()
+
+	dateSeparators = " /\\_.-"
+)
+
+func findStrengthMatches(password []rune) []strengthMatch {
+
+	var matches []strengthMatch
+	matches = append(matches, dictionaryMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, repeatMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+	matches = append(matches, keyboardMatches(password)...)
+	return matches
+}
+
+// dictionaryMatches finds the words of the dictionaries in password, also when
+// reversed or spelled in l33t, whatever their case.
+func dictionaryMatches(password []rune) []strengthMatch {
+
+	lower := []rune(strings.ToLower(string(password)))
+	if len(lower) != len(password) {
+		// Some characters change their length in lower case
+		lower = make([]rune, len(password))
+		for i, char := range password {
+			lower[i] = unicode.ToLower(char)
+		}
+	}
+
+	var matches []strengthMatch
+	for i := range lower {
+
+		// Words lower[i:j+1] could stand for in l33t, grown a character at a
+		// time, if it has any l33t character
+		variants, l33t := [][]rune{{}}, false
+		for j := i; j < len(lower) && j-i < strengthMaxWordLength; j++ {
+
+			token, word := password[i:j+1], lower[i:j+1]
+			if rank, ok := wordRank(string(word)); ok {
+				matches = append(matches, strengthMatch{dictionaryPattern, i, j, float64(rank) * uppercaseVariations(token)})
+			}
+
+			if len(word) > 1 {
+				if rank, ok := wordRank(string(reverseRunes(word))); ok {
+					matches = append(matches, strengthMatch{dictionaryPattern, i, j, float64(rank) * uppercaseVariations(token) * 2})
+				}
+			}
+
+			variants = unl33tVariants(variants, lower[j])
+			if _, ok := normalize.LeetLetters(lower[j]); ok {
+				l33t = true
+			}
+			if !l33t {
+				continue
+			}
+			for _, variant := range variants {
+				if rank, ok := wordRank(string(variant)); ok {
+					matches = append(matches, strengthMatch{dictionaryPattern, i, j, float64(rank) * uppercaseVariations(token) * l33tVariations(word, variant)})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// wordRank returns the best rank of word among the dictionaries.
+func wordRank(word string) (int, bool) {
+
+	best, found := 0, false
+	for _, dictionary := range strengthDictionaries {
+		if rank, ok := dictionary[word]; ok && (!found || rank < best) {
+			best, found = rank, true
+		}
+	}
+	return best, found
+}
+
+// uppercaseVariations is the number of ways the letters of token could have
+// been capitalised, counting capitalising the first or last letter, or all of
+// them, as a single extra guess.
+func uppercaseVariations(token []rune) float64 {
+
+	upper, lower := 0, 0
+	for _, char := range token {
+		if unicode.IsUpper(char) {
+			upper++
+		} else if unicode.IsLower(char) {
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || unicode.IsUpper(token[0]) && upper == 1 || unicode.IsUpper(token[len(token)-1]) && upper == 1 {
+		return 2
+	}
+	return binomialSum(upper+lower, upper, lower)
+}
+
+// unl33tVariants extends the words a token could stand for, replacing its
+// l33t characters by letters, with the next character of the token. Only the
+// variants starting a dictionary word are kept, and as characters standing
+// for several letters make them grow quickly, only the first of those.
+func unl33tVariants(variants [][]rune, char rune) [][]rune {
+
+	const maxVariants = 16
+
+	letters, ok := normalize.LeetLetters(char)
+	if !ok {
+		letters = []rune{char}
+	}
+
+	var next [][]rune
+	for _, variant := range variants {
+		for _, letter := range letters {
+			if len(next) == maxVariants {
+				return next
+			}
+			extended := append(append(make([]rune, 0, len(variant)+1), variant...), letter)
+			if strengthWordPrefixes[string(extended)] {
+				next = append(next, extended)
+			}
+		}
+	}
+	return next
+}
+
+// l33tVariations is the number of ways the letters of word could have been
+// spelled in l33t as token is.
+func l33tVariations(token, word []rune) float64 {
+
+	type substitution struct{ from, to rune }
+	substitutions := make(map[substitution]bool)
+	for i := range token {
+		if token[i] != word[i] {
+			substitutions[substitution{token[i], word[i]}] = true
+		}
+	}
+
+	variations := 1.0
+	for sub := range substitutions {
+		substituted, unsubstituted := 0, 0
+		for _, char := range token {
+			if char == sub.from {
+				substituted++
+			} else if char == sub.to {
+				unsubstituted++
+			}
+		}
+		if substituted == 0 || unsubstituted == 0 {
+			variations *= 2
+		} else {
+			variations *= binomialSum(substituted+unsubstituted, substituted, unsubstituted)
+		}
+	}
+	return variations
+}
+
+// binomialSum is the sum of the ways of choosing from 1 up to the least of a
+// and b elements out of n.
+func binomialSum(n, a, b int) float64 {
+
+	sum := 0.0
+	for k := 1; k <= a && k <= b; k++ {
+		sum += binomial(n, k)
+	}
+	return sum
+}
+
+func binomial(n, k int) float64 {
+
+	if k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// sequenceMatches finds runs of at least three characters following each
+// other at the same distance, as abc, 2468 or zyx.
+func sequenceMatches(password []rune) []strengthMatch {
+
+	var matches []strengthMatch
+	for i := 0; i < len(password)-2; {
+
+		delta := password[i+1] - password[i]
+		j := i + 1
+		for j+1 < len(password) && password[j+1]-password[j] == delta {
+			j++
+		}
+
+		if j-i >= 2 && delta != 0 && -5 <= delta && delta <= 5 {
+			matches = append(matches, strengthMatch{sequencePattern, i, j, sequenceGuesses(password[i:j+1], delta > 0)})
+			i = j
+			continue
+		}
+		i++
+	}
+
+	return matches
+}
+
+func sequenceGuesses(token []rune, ascending bool) float64 {
+
+	var base float64
+	switch first := token[0]; {
+	case strings.ContainsRune("aAzZ019", first):
+		// Obvious starting points
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !ascending {
+		base *= 2
+	}
+	return base * float64(len(token))
+}
+
+// repeatMatches finds characters or groups of characters repeated one after
+// the other, as aaa or abcabc. Their guesses are those of the repeated group
+// times the repetitions.
+func repeatMatches(password []rune) []strengthMatch {
+
+	var matches []strengthMatch
+	for i := 0; i < len(password); {
+
+		bestBase, bestCount := 0, 0
+		for base := 1; i+2*base <= len(password); base++ {
+			count := 1
+			for i+(count+1)*base <= len(password) && equalRunes(password[i:i+base], password[i+count*base:i+(count+1)*base]) {
+				count++
+			}
+			if count > 1 && base*count > bestBase*bestCount {
+				bestBase, bestCount = base, count
+			}
+		}
+
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		baseGuesses, _ := mostGuessableSequence(password[i : i+bestBase])
+		matches = append(matches, strengthMatch{repeatPattern, i, i + bestBase*bestCount - 1, baseGuesses * float64(bestCount)})
+		i += bestBase * bestCount
+	}
+
+	return matches
+}
+
+func equalRunes(a, b []rune) bool {
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// dateMatches finds dates written as digits, with or without separators
+// between day, month and year, and years on their own.
+func dateMatches(password []rune) []strengthMatch {
+
+	var matches []strengthMatch
+	for i := range password {
+		for j := i + 3; j < len(password) && j < i+10; j++ {
+
+			token := password[i : j+1]
+			if year, ok := yearOf(token); ok {
+				matches = append(matches, strengthMatch{datePattern, i, j, yearGuesses(year)})
+			}
+			if year, ok := dateWithoutSeparator(token); ok {
+				matches = append(matches, strengthMatch{datePattern, i, j, yearGuesses(year) * 365})
+			}
+			if year, ok := dateWithSeparator(token); ok {
+				matches = append(matches, strengthMatch{datePattern, i, j, yearGuesses(year) * 365 * 4})
+			}
+		}
+	}
+
+	return matches
+}
+
+func yearGuesses(year int) float64 {
+	return math.Max(math.Abs(float64(year-referenceYear)), 20)
+}
+
+// yearOf returns the year token is, when a recent one.
+func yearOf(token []rune) (int, bool) {
+
+	year, ok := atoi(token)
+	if !ok || len(token) != 4 || year < 1900 || year > 2099 {
+		return 0, false
+	}
+	return year, true
+}
+
+// dateWithoutSeparator returns the year of the date token is made of, trying
+// every way of splitting it in day, month and year.
+func dateWithoutSeparator(token []rune) (int, bool) {
+
+	splits := map[int][][2]int{
+		4: {{1, 2}, {2, 3}},
+		5: {{1, 3}, {2, 3}},
+		6: {{1, 2}, {2, 4}, {4, 5}},
+		7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+		8: {{2, 4}, {4, 6}},
+	}
+
+	for _, split := range splits[len(token)] {
+		first, ok1 := atoi(token[:split[0]])
+		second, ok2 := atoi(token[split[0]:split[1]])
+		third, ok3 := atoi(token[split[1]:])
+		if !ok1 || !ok2 || !ok3 {
+			continue
+		}
+		if year, ok := dateOf(first, second, third); ok {
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+// dateWithSeparator returns the year of the date token is when its parts are
+// separated twice by the same separator.
+func dateWithSeparator(token []rune) (int, bool) {
+
+	var separator rune
+	var parts [][]rune
+	start := 0
+	for i, char := range token {
+		if !strings.ContainsRune(dateSeparators, char) {
+			continue
+		}
+		if separator != 0 && char != separator {
+			return 0, false
+		}
+		separator = char
+		parts = append(parts, token[start:i])
+		start = i + 1
+	}
+	parts = append(parts, token[start:])
+
+	if len(parts) != 3 {
+		return 0, false
+	}
+	values := make([]int, 3)
+	for i, part := range parts {
+		value, ok := atoi(part)
+		if !ok || len(part) > 4 {
+			return 0, false
+		}
+		values[i] = value
+	}
+	return dateOf(values[0], values[1], values[2])
+}
+
+// dateOf returns the year of the date made of the values, either as year,
+// month and day, day, month and year or month, day and year. Years of two
+// digits are taken as the closest one to the reference year.
+func dateOf(first, second, third int) (int, bool) {
+
+	orders := [][3]int{{first, second, third}, {third, second, first}, {third, first, second}}
+	for _, order := range orders {
+		year, month, day := order[0], order[1], order[2]
+		if year < 100 {
+			year += 1900
+			if year < referenceYear-80 {
+				year += 100
+			}
+		}
+		if year >= 1000 && year <= 2099 && month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+func atoi(token []rune) (int, bool) {
+
+	if len(token) == 0 {
+		return 0, false
+	}
+	value := 0
+	for _, char := range token {
+		if char < '0' || char > '9' {
+			return 0, false
+		}
+		value = value*10 + int(char-'0')
+	}
+	return value, true
+}
+
+// keyboardMatches finds runs of at least three characters typed on adjacent
+// keys, on any of the known layouts.
+func keyboardMatches(password []rune) []strengthMatch {
+
+	var matches []strengthMatch
+	for _, graph := range keyboardGraphs {
+		for _, walk := range graph.walks(password, 3) {
+			matches = append(matches, strengthMatch{keyboardPattern, walk.i, walk.j, walk.guesses()})
+		}
+	}
+	return matches
+}
+
+// guesses counts the walks of the same length with up to the same turns,
+// from any starting key, and the ways of shifting their keys.
+func (kw keyboardWalk) guesses() float64 {
+
+	length := kw.j - kw.i + 1
+	startingPositions := float64(kw.graph.startingPositions())
+
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for turns := 1; turns <= kw.turns && turns <= i-1; turns++ {
+			guesses += binomial(i-1, turns-1) * startingPositions * math.Pow(kw.graph.averageDegree, float64(turns))
+		}
+	}
+
+	if kw.shifted > 0 {
+		unshifted := length - kw.shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			guesses *= binomialSum(length, kw.shifted, unshifted)
+		}
+	}
+	return guesses
+}
+
+func bruteforceMatch(password []rune, i, j int) strengthMatch {
+
+	length := j - i + 1
+	guesses := math.Pow(10, float64(length))
+	minimum := 51.0
+	if length == 1 {
+		minimum = 11
+	}
+	return strengthMatch{bruteforcePattern, i, j, math.Max(guesses, minimum)}
+}
+
+func reverseRunes(runes []rune) []rune {
+
+	reversed := make([]rune, len(runes))
+	for i, char := range runes {
+		reversed[len(runes)-1-i] = char
+	}
+	return reversed
+}
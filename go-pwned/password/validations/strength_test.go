@@ -0,0 +1,276 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStrengthDisabled(t *testing.T) {
+
+	strength := &Strength{Enabled: false, MinScore: 4}
+
+	ok, err := strength.Validate(context.Background(), "password")
+	if err != nil {
+		t.Errorf("Strength validator returned error: %q\n", err)
+	}
+	if ok != true {
+		t.Error("Was expecting ok to be true")
+	}
+}
+
+func TestStrengthValidationShouldFail(t *testing.T) {
+
+	tests := []struct {
+		scenario  string
+		minScore  int
+		passwords []string
+	}{
+		{
+			scenario:  "Common passwords and their variations are guessable",
+			minScore:  1,
+			passwords: []string{"Passw0rd", "P@ssw0rd", "drowssap", "iloveyou", "qwerty"},
+		},
+		{
+			scenario:  "Passwords only meeting character rules are guessable",
+			minScore:  3,
+			passwords: []string{"Aaaaaaa1!a", "Summer2024!", "Qwerty123!", "abcdef1990"},
+		},
+	}
+
+	for _, test := range tests {
+		strength := Strength{Enabled: true, MinScore: test.minScore}
+		for _, password := range test.passwords {
+			ok, err := strength.Validate(context.Background(), password)
+			if ok != false {
+				t.Errorf("Scenario '%s'. Was expecting ok to be false for '%s'\n", test.scenario, password)
+			}
+
+			var strengthErr *StrengthError
+			if !errors.As(err, &strengthErr) {
+				t.Errorf("Scenario '%s'. Expected a StrengthError for '%s', Got: %v\n", test.scenario, password, err)
+				continue
+			}
+			if strengthErr.Score >= test.minScore {
+				t.Errorf("Scenario '%s'. Expected '%s' to score below %d, Got: %d\n", test.scenario, password, test.minScore, strengthErr.Score)
+			}
+			if strings.Contains(strings.ToLower(err.Error()), strings.ToLower(password)) {
+				t.Errorf("Scenario '%s'. Error should not contain the password, Got: '%s'\n", test.scenario, err)
+			}
+		}
+	}
+}
+
+func TestStrengthValidationShouldPass(t *testing.T) {
+
+	strength := Strength{Enabled: true, MinScore: 3}
+	passwords := []string{"x7#Kq9!vLm2$Pz", "correcthorsebatterystaple", "Tr0ub4dour&3"}
+
+	for _, password := range passwords {
+		ok, err := strength.Validate(context.Background(), password)
+		if err != nil || !ok {
+			t.Errorf("Expected '%s' to be strong enough, Got: %v\n", password, err)
+		}
+	}
+}
+
+func TestStrengthShouldReportEstimation(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		strength Strength
+		password string
+		reported bool
+	}{
+		{
+			scenario: "Should report the estimation of an accepted password",
+			strength: Strength{Enabled: true, MinScore: 1},
+			password: "kT9#mQ2!vR",
+			reported: true,
+		},
+		{
+			scenario: "Should report the estimation of a rejected password",
+			strength: Strength{Enabled: true, MinScore: 3},
+			password: "Passw0rd",
+			reported: true,
+		},
+		{
+			scenario: "Should not report anything when disabled",
+			strength: Strength{Enabled: false},
+			password: "kT9#mQ2!vR",
+			reported: false,
+		},
+	}
+
+	for _, test := range tests {
+
+		report := &StrengthReport{}
+		test.strength.Validate(WithStrengthReport(context.Background(), report), test.password)
+
+		estimation, ok := report.Estimation()
+		if ok != test.reported {
+			t.Errorf("Scenario '%s'. Expected reported to be %t, Got: %t\n", test.scenario, test.reported, ok)
+			continue
+		}
+		if expected := Estimate(test.password); ok && estimation.Score != expected.Score {
+			t.Errorf("Scenario '%s'. Expected score %d, Got: %d\n", test.scenario, expected.Score, estimation.Score)
+		}
+	}
+}
+
+func TestEstimateShouldFindPatterns(t *testing.T) {
+
+	tests := []struct {
+		scenario        string
+		password        string
+		expectedPattern string
+		expectedScore   int
+	}{
+		{
+			scenario:        "Should find common passwords",
+			password:        "password",
+			expectedPattern: dictionaryPattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find words spelled in l33t",
+			password:        "P4ssw0rd",
+			expectedPattern: dictionaryPattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find words spelled with any l33t character normalization knows",
+			password:        "#unter",
+			expectedPattern: dictionaryPattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find sequences",
+			password:        "abcdef",
+			expectedPattern: sequencePattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find descending sequences",
+			password:        "97531",
+			expectedPattern: sequencePattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find repetitions",
+			password:        "xyzxyzxyz",
+			expectedPattern: repeatPattern,
+			expectedScore:   0,
+		},
+		{
+			scenario:        "Should find dates without separator",
+			password:        "19900512",
+			expectedPattern: datePattern,
+			expectedScore:   1,
+		},
+		{
+			scenario:        "Should find dates with separator",
+			password:        "12/05/1990",
+			expectedPattern: datePattern,
+			expectedScore:   1,
+		},
+		{
+			scenario:        "Should find keyboard walks",
+			password:        "zxcvfdsa",
+			expectedPattern: keyboardPattern,
+			expectedScore:   1,
+		},
+		{
+			scenario:        "Should find keyboard walks on the keypad",
+			password:        "0147852",
+			expectedPattern: keyboardPattern,
+			expectedScore:   1,
+		},
+		{
+			scenario:      "Should brute force random passwords",
+			password:      "x7#Kq9!vLm2$Pz",
+			expectedScore: 4,
+		},
+	}
+
+	for _, test := range tests {
+
+		estimation := Estimate(test.password)
+		if estimation.Score != test.expectedScore {
+			t.Errorf("Scenario '%s'. Expected score %d, Got: %d (%e guesses)\n", test.scenario, test.expectedScore, estimation.Score, estimation.Guesses)
+		}
+
+		if test.expectedPattern == "" {
+			if len(estimation.Patterns) != 0 {
+				t.Errorf("Scenario '%s'. Wasn't expecting patterns, Got: %v\n", test.scenario, estimation.Patterns)
+			}
+			continue
+		}
+		found := false
+		for _, pattern := range estimation.Patterns {
+			found = found || pattern == test.expectedPattern
+		}
+		if !found {
+			t.Errorf("Scenario '%s'. Expected pattern '%s', Got: %v\n", test.scenario, test.expectedPattern, estimation.Patterns)
+		}
+	}
+}
+
+func TestEstimateShouldGrowWithLength(t *testing.T) {
+
+	shorter := Estimate("kT9#mQ")
+	longer := Estimate("kT9#mQ2!vR")
+	if shorter.Guesses >= longer.Guesses {
+		t.Errorf("Expected more guesses for longer passwords, Got: %e and %e\n", shorter.Guesses, longer.Guesses)
+	}
+
+	// Passwords are only analysed up to a length, but are still estimated
+	if estimation := Estimate(strings.Repeat("kT9#mQ2!vR", 20)); estimation.Score != 4 {
+		t.Errorf("Expected long passwords to score %d, Got: %d\n", 4, estimation.Score)
+	}
+}
+
+func TestCrackTime(t *testing.T) {
+
+	tests := []struct {
+		seconds  float64
+		expected string
+	}{
+		{0.5, "less than a second"},
+		{1, "1 second"},
+		{90, "2 minutes"},
+		{3 * 3600, "3 hours"},
+		{2 * 365 * 24 * 3600, "2 years"},
+		{300 * 365 * 24 * 3600, "3 centuries"},
+		{1e20, "centuries"},
+	}
+
+	for _, test := range tests {
+		if crackTime := (Estimation{CrackTimeSeconds: test.seconds}).CrackTime(); crackTime != test.expected {
+			t.Errorf("Expected '%s' for %g seconds, Got: '%s'\n", test.expected, test.seconds, crackTime)
+		}
+	}
+}
+
+// BenchmarkEstimateWorstCase estimates passwords of the maximum length
+// analysed made of characters matching the most patterns, as digits are
+// dictionary words, dates, l33t and repetitions at once.
+func BenchmarkEstimateWorstCase(b *testing.B) {
+
+	passwords := map[string]string{
+		"repeated digit":  strings.Repeat("1", strengthMaxLength),
+		"repeated digits": strings.Repeat("19", strengthMaxLength/2),
+		"repeated letter": strings.Repeat("a", strengthMaxLength),
+		"repeated word":   strings.Repeat("password", strengthMaxLength/8),
+	}
+
+	for name, password := range passwords {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Estimate(password)
+			}
+		})
+	}
+}
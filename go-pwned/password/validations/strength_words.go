@@ -0,0 +1,144 @@
+package validations
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Word lists the strength estimator looks passwords up in, most common first.
+// The rank of a word is the number of guesses an attacker trying the list in
+// order needs to find it.
+var strengthDictionaries = map[string]map[string]int{
+	"passwords": rankedWords(`
+		123456 password 12345678 qwerty 123456789 12345 1234 111111 1234567 dragon
+		123123 baseball abc123 football monkey letmein shadow master 666666
+		qwertyuiop 123321 mustang 1234567890 michael 654321 superman 1qaz2wsx 7777777 121212
+		000000 qazwsx 123qwe killer trustno1 jordan jennifer zxcvbnm asdfgh hunter
+		buster soccer harley batman andrew tigger sunshine iloveyou 2000 charlie robert
+		thomas hockey ranger daniel starwars klaster 112233 george computer michelle jessica
+		pepper 1111 zxcvbn 555555 11111111 131313 freedom 777777 pass maggie
+		159753 aaaaaa ginger princess joshua cheese amanda summer love ashley
+		nicole chelsea matthew access yankees 987654321 dallas austin
+		thunder taylor matrix william corvette hello martin heather secret merlin
+		diamond 1234qwer gfhjkm hammer silver 222222 88888888 anthony justin test
+		bailey q1w2e3r4t5 patrick internet scooter orange 11111 golfer cookie richard
+		samantha bigdog guitar jackson whatever mickey chicken sparky snoopy maverick
+		phoenix camaro peanut morgan welcome falcon cowboy ferrari samsung andrea
+		smokey steelers joseph mercedes dakota arsenal eagles melissa boomer booboo
+		spider nascar monster tigers yellow xxxxxx 123123123 gateway marina diablo
+		bulldog qwer1234 compaq purple banana junior hannah 123654 porsche
+		lakers iceman money cowboys 987654 london tennis 999999 ncc1701 coffee
+		scooby 0000 miller boston q1w2e3r4 brandon yamaha chester mother forever
+		johnny edward 333333 oliver redsox player nikita knight fender barney
+		midnight please brandy chicago badboy slayer rangers charles angel flower
+		bigdaddy rabbit wizard jasper enter rachel chris steven winner adidas
+		victoria natasha 1q2w3e4r jasmine winter prince marine ghbdtn
+		fishing cocacola casper james 232323 raiders 888888 marlboro gandalf asdfasdf
+		crystal 87654321 12344321 golf 8675309 qwe123 admin passw0rd password1
+		password123 welcome1 letmein1 abc12345 iloveyou1 qwerty123 1q2w3e 123abc
+		changeme default login root toor guest administrator sample demo pa55word
+		p@ssw0rd p@ssword passwort motdepasse contrasena senha wachtwoord
+	`),
+	"english": rankedWords(`
+		the and for you that this with have from they know want been good much
+		some time very when come here just like long make many more only over such
+		take than them well were will about after again could every first great
+		house just little never other place right small sound still think three
+		where which world would write year back give most name people thing
+		water word work call down find hand high keep last life line look made
+		mean move must need part play point read same show side tell turn home
+		light night open river school family friend money music story summer
+		winter spring autumn garden happy heart horse island letter mountain
+		number office orange paper party peace picture planet purple queen rainbow
+		secret silver simple sister brother mother father window yellow green blue
+		black white brown golden dragon tiger lion eagle falcon wolf bear monkey
+		rabbit turtle kitten puppy flower sunshine moon star stars cloud storm
+		thunder shadow fire water earth wind ocean forest desert castle kingdom
+		knight wizard magic power energy freedom justice victory winner champion
+		hero legend master captain doctor teacher student soldier pilot angel
+		devil ghost spirit monster hunter killer ninja pirate cowboy football
+		baseball soccer hockey tennis golf basketball chocolate coffee cookie
+		cheese banana apple cherry lemon pepper sugar honey butter bread pizza
+		computer internet network system server access login admin welcome hello
+		please thanks sorry love lovely beautiful pretty sweet sweetheart baby
+		darling friends forever always together letmein trust trustno pass word
+		password secure security private public change default test testing
+		summer beach holiday travel school college university company business
+		january february march april may june july august september october
+		november december monday tuesday wednesday thursday friday saturday sunday
+		spring season weekend morning evening birthday christmas easter
+		red pink gray grey violet crimson diamond crystal emerald ruby pearl
+		gold platinum steel iron stone rock metal cloud sky blue
+	`),
+	"names": rankedWords(`
+		james john robert michael william david richard charles joseph thomas
+		christopher daniel paul mark donald george kenneth steven edward brian
+		ronald anthony kevin jason matthew gary timothy jose larry jeffrey frank
+		scott eric stephen andrew raymond gregory joshua jerry dennis walter
+		patrick peter harold douglas henry carl arthur ryan roger joe juan jack
+		albert jonathan justin terry gerald keith samuel willie ralph lawrence
+		nicholas roy benjamin bruce brandon adam harry fred wayne billy steve
+		louis jeremy aaron randy howard eugene carlos russell bobby victor martin
+		mary patricia linda barbara elizabeth jennifer maria susan margaret dorothy
+		lisa nancy karen betty helen sandra donna carol ruth sharon michelle laura
+		sarah kimberly deborah jessica shirley cynthia angela melissa brenda amy
+		anna rebecca virginia kathleen pamela martha debra amanda stephanie
+		carolyn christine marie janet catherine frances ann joyce diane alice
+		julie heather teresa doris gloria evelyn jean cheryl mildred katherine
+		joan ashley judith rose janice kelly nicole judy christina kathy theresa
+		beverly denise tammy irene jane lori rachel marilyn andrea kathryn louise
+		sara anne jacqueline wanda bonnie julia ruby lois tina phyllis norma
+		paula diana annie lillian emily robin peggy crystal gladys rita dawn
+		smith johnson williams jones brown davis miller wilson moore taylor
+		anderson jackson white harris thompson garcia martinez robinson clark
+		rodriguez lewis lee walker hall allen young king wright lopez hill green
+		adams baker nelson carter mitchell perez roberts turner phillips campbell
+	`),
+}
+
+var (
+	// Length in characters of the longest word of strengthDictionaries, so
+	// longer parts of passwords aren't looked up.
+	strengthMaxWordLength = longestWord(strengthDictionaries)
+	// Beginnings of the words of strengthDictionaries, so l33t variants which
+	// can't become a word are dropped as soon as possible.
+	strengthWordPrefixes = wordPrefixes(strengthDictionaries)
+)
+
+func rankedWords(list string) map[string]int {
+
+	ranked := make(map[string]int)
+	for rank, word := range strings.Fields(list) {
+		// Words listed twice keep their best rank
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = rank + 1
+		}
+	}
+	return ranked
+}
+
+func longestWord(dictionaries map[string]map[string]int) (longest int) {
+
+	for _, dictionary := range dictionaries {
+		for word := range dictionary {
+			if length := utf8.RuneCountInString(word); length > longest {
+				longest = length
+			}
+		}
+	}
+	return
+}
+
+func wordPrefixes(dictionaries map[string]map[string]int) map[string]bool {
+
+	prefixes := make(map[string]bool)
+	for _, dictionary := range dictionaries {
+		for word := range dictionary {
+			runes := []rune(word)
+			for i := 1; i <= len(runes); i++ {
+				prefixes[string(runes[:i])] = true
+			}
+		}
+	}
+	return prefixes
+}
@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"unicode"
@@ -13,7 +14,7 @@ type Symbol struct {
 	AllowedSymbols string `yaml:"allowedSymbols"`
 }
 
-func (s *Symbol) Validate(password string) (bool, error) {
+func (s *Symbol) Validate(ctx context.Context, password string) (bool, error) {
 
 	if s.Enabled {
 		totalSymbols := countSymbols(password)
@@ -1,6 +1,7 @@
 package validations
 
 import (
+	"context"
 	"strings"
 	"testing"
 )
@@ -13,7 +14,7 @@ func TestSymbolDisabled(t *testing.T) {
 		AllowedSymbols: "",
 	}
 
-	ok, result := symbolRules.Validate("passw0rd")
+	ok, result := symbolRules.Validate(context.Background(), "passw0rd")
 
 	if result != nil {
 		t.Errorf("Symbol validator returned error: %q\n", result)
@@ -76,7 +77,7 @@ func TestValidateSymbolShouldFail(t *testing.T) {
 	for _, test := range tests {
 		sr := test.symbolRules
 		for _, password := range test.passwords {
-			ok, expected := sr.Validate(password)
+			ok, expected := sr.Validate(context.Background(), password)
 
 			if expected == nil {
 				t.Errorf("Expected error for scenario '%s'\n", test.scenario)
@@ -131,7 +132,7 @@ func TestValidateSymbolShouldPass(t *testing.T) {
 	for _, test := range tests {
 		sr := test.symbolRules
 		for _, password := range test.passwords {
-			ok, error := sr.Validate(password)
+			ok, error := sr.Validate(context.Background(), password)
 
 			if error != nil {
 				t.Errorf("Got unexpected error %s for scenario %s\n", error.Error(), test.scenario)
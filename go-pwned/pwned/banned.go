@@ -0,0 +1,64 @@
+package pwned
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// BannedList holds passwords an organisation doesn't allow, e.g. its own name
+// or products, regardless of them having been seen in data breaches. Passwords
+// are compared ignoring case.
+type BannedList struct {
+	passwords map[string]struct{}
+}
+
+// ReadBannedList reads one password per line, skipping empty lines and lines
+// starting with '#'.
+func ReadBannedList(r io.Reader) (*BannedList, error) {
+
+	list := &BannedList{passwords: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.passwords[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// OpenBannedList loads the banned list found at path.
+func OpenBannedList(path string) (*BannedList, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBannedList(file)
+}
+
+func (bl *BannedList) Len() int {
+	return len(bl.passwords)
+}
+
+// Check reports banned passwords as insecure. As they don't come from a data
+// breach, no occurrences are reported for them.
+func (bl *BannedList) Check(password string) (Result, error) {
+
+	if _, banned := bl.passwords[strings.ToLower(password)]; banned {
+		return Result{Secure: false}, nil
+	}
+
+	return Result{Secure: true}, nil
+}
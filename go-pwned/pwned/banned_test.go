@@ -0,0 +1,55 @@
+package pwned
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBannedListCheck(t *testing.T) {
+
+	list, err := ReadBannedList(strings.NewReader("# Company names\nAcme\n\n  AcmeCorp2022  \nsummer-picnic\n"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	if list.Len() != 3 {
+		t.Errorf("Expected 3 banned passwords, Got: %d\n", list.Len())
+	}
+
+	tests := []struct {
+		scenario       string
+		password       string
+		expectedSecure bool
+	}{
+		{
+			scenario:       "Should reject banned password",
+			password:       "Acme",
+			expectedSecure: false,
+		},
+		{
+			scenario:       "Should reject banned password ignoring case",
+			password:       "acmecorp2022",
+			expectedSecure: false,
+		},
+		{
+			scenario:       "Should accept password not banned",
+			password:       "AcmeCorp2023",
+			expectedSecure: true,
+		},
+		{
+			scenario:       "Should not ban comments",
+			password:       "# Company names",
+			expectedSecure: true,
+		},
+	}
+
+	for _, test := range tests {
+		result, err := list.Check(test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result.Secure != test.expectedSecure {
+			t.Errorf("Scenario '%s'. Got %t, expected %t\n", test.scenario, result.Secure, test.expectedSecure)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package pwned
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	bloomMagic      = "PWNBLM"
+	bloomVersion    = 1
+	bloomHeaderSize = 20
+	bloomMinHash    = 16
+)
+
+type (
+	// Bloom configures the bloom filter backend. A positive answer from the
+	// filter may be a false positive, so it can be confirmed against the
+	// pwned service.
+	Bloom struct {
+		Path    string `yaml:"path"`
+		Confirm bool   `yaml:"confirm"`
+	}
+
+	// BloomFilter is a probabilistic set of password hashes. It never misses a
+	// hash which was added to it, but may report hashes which weren't.
+	BloomFilter struct {
+		bits   []uint64
+		size   uint64
+		hashes uint32
+	}
+)
+
+// NewBloomFilter sizes a filter to hold items hashes with the given false
+// positive rate.
+func NewBloomFilter(items uint64, falsePositiveRate float64) (*BloomFilter, error) {
+
+	if items == 0 {
+		return nil, fmt.Errorf("bloom filter needs to hold at least one item")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate should be between 0 and 1, got %g", falsePositiveRate)
+	}
+
+	size := uint64(math.Ceil(-float64(items) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint32(math.Round(float64(size) / float64(items) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return newBloomFilter(size, hashes), nil
+}
+
+func newBloomFilter(size uint64, hashes uint32) *BloomFilter {
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add inserts a raw password hash in the filter.
+func (bf *BloomFilter) Add(hash []byte) error {
+
+	if len(hash) < bloomMinHash {
+		return fmt.Errorf("hash should be at least %d bytes long, got %d", bloomMinHash, len(hash))
+	}
+
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(bf.hashes); i++ {
+		position := (h1 + i*h2) % bf.size
+		bf.bits[position/64] |= 1 << (position % 64)
+	}
+
+	return nil
+}
+
+// Contains reports whether a raw password hash may have been added to the
+// filter.
+func (bf *BloomFilter) Contains(hash []byte) bool {
+
+	if len(hash) < bloomMinHash {
+		return false
+	}
+
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(bf.hashes); i++ {
+		position := (h1 + i*h2) % bf.size
+		if bf.bits[position/64]&(1<<(position%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHashes derives the filter positions from the password hash itself, as
+// it is already uniformly distributed, using double hashing.
+func bloomHashes(hash []byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(hash[:8]), binary.BigEndian.Uint64(hash[8:16]) | 1
+}
+
+// WriteTo persists the filter to w.
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+
+	writer := bufio.NewWriter(w)
+
+	header := make([]byte, bloomHeaderSize)
+	copy(header, bloomMagic)
+	header[6] = bloomVersion
+	binary.BigEndian.PutUint32(header[8:12], bf.hashes)
+	binary.BigEndian.PutUint64(header[12:20], bf.size)
+	if _, err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	word := make([]byte, 8)
+	for _, bits := range bf.bits {
+		binary.BigEndian.PutUint64(word, bits)
+		if _, err := writer.Write(word); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(bloomHeaderSize + 8*len(bf.bits)), writer.Flush()
+}
+
+// ReadBloomFilter loads a filter persisted with WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, bloomHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("could not read bloom filter header: %w", err)
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic || header[6] != bloomVersion {
+		return nil, fmt.Errorf("not a valid bloom filter")
+	}
+
+	hashes := binary.BigEndian.Uint32(header[8:12])
+	size := binary.BigEndian.Uint64(header[12:20])
+	if hashes == 0 || size == 0 {
+		return nil, fmt.Errorf("bloom filter is corrupted")
+	}
+
+	bf := newBloomFilter(size, hashes)
+	word := make([]byte, 8)
+	for i := range bf.bits {
+		if _, err := io.ReadFull(reader, word); err != nil {
+			return nil, fmt.Errorf("bloom filter is truncated: %w", err)
+		}
+		bf.bits[i] = binary.BigEndian.Uint64(word)
+	}
+
+	return bf, nil
+}
+
+// OpenBloomFilter loads the filter persisted at path.
+func OpenBloomFilter(path string) (*BloomFilter, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBloomFilter(file)
+}
+
+// BuildBloomFilter creates a filter from the hash list at path, one hash per
+// line optionally followed by its count (HASH:COUNT).
+func BuildBloomFilter(path string, falsePositiveRate float64) (*BloomFilter, error) {
+
+	items, err := countBloomItems(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := NewBloomFilter(items, falsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = readHashList(path, func(hash []byte) error {
+		return bf.Add(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bf, nil
+}
+
+func countBloomItems(path string) (uint64, error) {
+
+	var items uint64
+	err := readHashList(path, func(hash []byte) error {
+		items++
+		return nil
+	})
+
+	return items, err
+}
+
+func readHashList(path string, process func(hash []byte) error) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		if separator := strings.LastIndex(line, ":"); separator >= 0 {
+			line = line[:separator]
+		}
+
+		hash, err := hex.DecodeString(line)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid hash '%s'", lineNumber, line)
+		}
+		if err := process(hash); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
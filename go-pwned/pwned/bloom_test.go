@@ -0,0 +1,206 @@
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func testHash(value string) []byte {
+	hash := sha1.Sum([]byte(value))
+	return hash[:]
+}
+
+func TestBloomFilterShouldNotMissAddedHashes(t *testing.T) {
+
+	filter, err := NewBloomFilter(1000, 0.01)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if err := filter.Add(testHash(fmt.Sprintf("password-%d", i))); err != nil {
+			t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !filter.Contains(testHash(fmt.Sprintf("password-%d", i))) {
+			t.Fatalf("Expected 'password-%d' to be in the filter\n", i)
+		}
+	}
+}
+
+func TestBloomFilterShouldKeepFalsePositiveRate(t *testing.T) {
+
+	const items = 10000
+	filter, _ := NewBloomFilter(items, 0.01)
+	for i := 0; i < items; i++ {
+		filter.Add(testHash(fmt.Sprintf("password-%d", i)))
+	}
+
+	falsePositives := 0
+	for i := 0; i < items; i++ {
+		if filter.Contains(testHash(fmt.Sprintf("not-added-%d", i))) {
+			falsePositives++
+		}
+	}
+
+	// Allow some margin over the configured rate
+	if rate := float64(falsePositives) / items; rate > 0.02 {
+		t.Errorf("Expected a false positive rate around %g, Got: %g\n", 0.01, rate)
+	}
+}
+
+func TestNewBloomFilterShouldRejectInvalidParameters(t *testing.T) {
+
+	tests := []struct {
+		scenario          string
+		items             uint64
+		falsePositiveRate float64
+	}{
+		{"Should reject an empty filter", 0, 0.01},
+		{"Should reject a zero false positive rate", 10, 0},
+		{"Should reject a false positive rate of one", 10, 1},
+	}
+
+	for _, test := range tests {
+		if _, err := NewBloomFilter(test.items, test.falsePositiveRate); err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+		}
+	}
+
+	filter, _ := NewBloomFilter(10, 0.01)
+	if err := filter.Add([]byte{0x01, 0x02}); err == nil {
+		t.Error("Was expecting an error adding a hash too short")
+	}
+}
+
+func TestBloomFilterShouldBePersisted(t *testing.T) {
+
+	filter, _ := NewBloomFilter(100, 0.01)
+	filter.Add(testHash("Passw0rd"))
+
+	var buffer bytes.Buffer
+	if _, err := filter.WriteTo(&buffer); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	loaded, err := ReadBloomFilter(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	if !loaded.Contains(testHash("Passw0rd")) {
+		t.Error("Expected the loaded filter to contain 'Passw0rd'")
+	}
+
+	if _, err := ReadBloomFilter(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1])); err == nil {
+		t.Error("Was expecting an error for a truncated filter")
+	}
+	if _, err := ReadBloomFilter(bytes.NewReader([]byte("not a bloom filter at all"))); err == nil {
+		t.Error("Was expecting an error for an invalid filter")
+	}
+}
+
+func TestBuildBloomFilterFromHashList(t *testing.T) {
+
+	path := writeTestDataset(t, "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n"+
+		"\n"+
+		"EBFC7910077770C8340F63CD2DCA2AC1F120444F\n")
+
+	filter, err := BuildBloomFilter(path, 0.001)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	for _, password := range []string{"ThisIsPassword1", "Passw0rd"} {
+		if !filter.Contains(testHash(password)) {
+			t.Errorf("Expected '%s' to be in the filter\n", password)
+		}
+	}
+
+	if _, err := BuildBloomFilter(writeTestDataset(t, "not-an-hash\n"), 0.001); err == nil {
+		t.Error("Was expecting an error for an invalid hash list")
+	}
+}
+
+func TestCheckInBloomMode(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	filter, _ := NewBloomFilter(10, 0.001)
+	filter.Add(testHash("Passw0rd"))
+
+	// Simulate a false positive by adding the hash for a password the pwned
+	// service doesn't know about
+	falsePositive, _ := hex.DecodeString(encodeToSHA1("Passw0rdSec*"))
+	filter.Add(falsePositive)
+
+	tests := []struct {
+		scenario         string
+		password         string
+		confirm          bool
+		expected         Result
+		expectedRequests int
+	}{
+		{
+			scenario:         "Should accept a password not in the filter without confirming it",
+			password:         "ThisIsPassword1",
+			confirm:          true,
+			expected:         Result{Secure: true},
+			expectedRequests: 0,
+		},
+		{
+			scenario:         "Should reject a password in the filter",
+			password:         "Passw0rd",
+			confirm:          false,
+			expected:         Result{Secure: false},
+			expectedRequests: 0,
+		},
+		{
+			scenario:         "Should confirm a password in the filter",
+			password:         "Passw0rd",
+			confirm:          true,
+			expected:         Result{Secure: false, Occurrences: 4},
+			expectedRequests: 1,
+		},
+		{
+			scenario:         "Should accept a false positive once confirmed",
+			password:         "Passw0rdSec*",
+			confirm:          true,
+			expected:         Result{Secure: true},
+			expectedRequests: 1,
+		},
+	}
+
+	for _, test := range tests {
+
+		requests := fake.Requests()
+		pwned := Pwned{Enabled: true, Mode: ModeBloom, Timeout: 2, URL: server.URL + "/range/", Bloom: Bloom{Confirm: test.confirm}, bloom: filter}
+
+		result, err := pwned.Check(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result != test.expected {
+			t.Errorf("Scenario '%s'. Expected: %+v, Got: %+v\n", test.scenario, test.expected, result)
+		}
+		if made := fake.Requests() - requests; made != test.expectedRequests {
+			t.Errorf("Scenario '%s'. Expected %d requests, Got: %d\n", test.scenario, test.expectedRequests, made)
+		}
+	}
+
+	notLoaded := Pwned{Enabled: true, Mode: ModeBloom}
+	if _, err := notLoaded.Check(context.Background(), "Passw0rd"); err == nil {
+		t.Error("Was expecting an error if the bloom filter is not loaded")
+	}
+}
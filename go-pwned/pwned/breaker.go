@@ -0,0 +1,187 @@
+package pwned
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+var ErrCircuitOpen = errors.New("pwned service circuit breaker is open")
+
+type (
+	BreakerState int
+
+	// CircuitBreaker opens the circuit after ConsecutiveFailures failed requests
+	// in a row, or once ErrorRate of the requests seen within WindowSeconds have
+	// failed (as long as there were at least MinRequests). While open requests
+	// are rejected straight away, and after OpenSeconds up to HalfOpenRequests
+	// probes are let through to decide whether to close it again.
+	CircuitBreaker struct {
+		Enabled             bool          `yaml:"enabled"`
+		ConsecutiveFailures int           `yaml:"consecutiveFailures"`
+		ErrorRate           float64       `yaml:"errorRate"`
+		MinRequests         int           `yaml:"minRequests"`
+		Window              time.Duration `yaml:"windowSeconds"`
+		OpenTimeout         time.Duration `yaml:"openSeconds"`
+		HalfOpenRequests    int           `yaml:"halfOpenRequests"`
+	}
+
+	circuitBreaker struct {
+		mutex   sync.Mutex
+		config  CircuitBreaker
+		metrics MetricService
+		now     func() time.Time
+
+		state               BreakerState
+		generation          uint64
+		consecutiveFailures int
+		windowStart         time.Time
+		windowRequests      int
+		windowFailures      int
+		openedAt            time.Time
+		probesInFlight      int
+		probesSucceeded     int
+	}
+)
+
+func (bs BreakerState) String() string {
+	switch bs {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func newCircuitBreaker(config CircuitBreaker, metrics MetricService) *circuitBreaker {
+
+	if config.HalfOpenRequests < 1 {
+		config.HalfOpenRequests = 1
+	}
+
+	cb := &circuitBreaker{config: config, metrics: metrics, now: time.Now}
+	cb.windowStart = cb.now()
+	metrics.CircuitBreakerState(int(BreakerClosed))
+	return cb
+}
+
+// allow reports whether a request can be made to the pwned service. Every
+// allowed request has to be followed by a call to record with its outcome and
+// the generation returned, so outcomes of requests allowed before the breaker
+// changed state are ignored.
+func (cb *circuitBreaker) allow() (uint64, error) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if cb.now().Sub(cb.openedAt) < cb.config.OpenTimeout {
+			return cb.generation, ErrCircuitOpen
+		}
+		cb.setState(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if cb.probesInFlight+cb.probesSucceeded >= cb.config.HalfOpenRequests {
+			return cb.generation, ErrCircuitOpen
+		}
+		cb.probesInFlight++
+	}
+
+	return cb.generation, nil
+}
+
+func (cb *circuitBreaker) record(generation uint64, err error) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case BreakerClosed:
+		cb.recordClosed(err)
+	case BreakerHalfOpen:
+		cb.probesInFlight--
+		if err != nil {
+			cb.setState(BreakerOpen)
+			return
+		}
+		cb.probesSucceeded++
+		if cb.probesSucceeded >= cb.config.HalfOpenRequests {
+			cb.setState(BreakerClosed)
+		}
+	}
+}
+
+// release gives back a request allowed by the breaker without recording its
+// outcome, as when it was cancelled by the caller.
+func (cb *circuitBreaker) release(generation uint64) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if generation == cb.generation && cb.state == BreakerHalfOpen {
+		cb.probesInFlight--
+	}
+}
+
+func (cb *circuitBreaker) recordClosed(err error) {
+
+	now := cb.now()
+	if cb.config.Window > 0 && now.Sub(cb.windowStart) >= cb.config.Window {
+		cb.windowStart = now
+		cb.windowRequests = 0
+		cb.windowFailures = 0
+	}
+
+	cb.windowRequests++
+	if err == nil {
+		cb.consecutiveFailures = 0
+		return
+	}
+	cb.windowFailures++
+	cb.consecutiveFailures++
+
+	tooManyConsecutive := cb.config.ConsecutiveFailures > 0 && cb.consecutiveFailures >= cb.config.ConsecutiveFailures
+	tooManyInWindow := cb.config.ErrorRate > 0 && cb.windowRequests >= cb.config.MinRequests &&
+		float64(cb.windowFailures)/float64(cb.windowRequests) >= cb.config.ErrorRate
+
+	if tooManyConsecutive || tooManyInWindow {
+		cb.setState(BreakerOpen)
+	}
+}
+
+func (cb *circuitBreaker) setState(state BreakerState) {
+
+	cb.state = state
+	cb.generation++
+	cb.consecutiveFailures = 0
+	cb.windowStart = cb.now()
+	cb.windowRequests = 0
+	cb.windowFailures = 0
+	cb.probesInFlight = 0
+	cb.probesSucceeded = 0
+	if state == BreakerOpen {
+		cb.openedAt = cb.now()
+	}
+
+	cb.metrics.CircuitBreakerState(int(state))
+}
+
+func (cb *circuitBreaker) currentState() BreakerState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.state
+}
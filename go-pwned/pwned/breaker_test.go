@@ -0,0 +1,218 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+var errTestUpstream = errors.New("test upstream error")
+
+type testClock struct {
+	now time.Time
+}
+
+func (tc *testClock) Now() time.Time {
+	return tc.now
+}
+
+func newTestBreaker(config CircuitBreaker, metrics MetricService, clock *testClock) *circuitBreaker {
+	breaker := newCircuitBreaker(config, metrics)
+	breaker.now = clock.Now
+	breaker.windowStart = clock.now
+	return breaker
+}
+
+// call simulates a request to the pwned service going through the breaker
+func call(breaker *circuitBreaker, err error) error {
+	generation, allowErr := breaker.allow()
+	if allowErr != nil {
+		return allowErr
+	}
+	breaker.record(generation, err)
+	return err
+}
+
+func TestCircuitBreakerShouldOpenAfterConsecutiveFailures(t *testing.T) {
+
+	clock := &testClock{time.Now()}
+	breaker := newTestBreaker(CircuitBreaker{ConsecutiveFailures: 3, OpenTimeout: 10 * time.Second}, noopMetrics{}, clock)
+
+	call(breaker, errTestUpstream)
+	call(breaker, errTestUpstream)
+	call(breaker, nil)
+	call(breaker, errTestUpstream)
+	call(breaker, errTestUpstream)
+	if state := breaker.currentState(); state != BreakerClosed {
+		t.Errorf("A success should reset consecutive failures. Expected state: %s, Got: %s\n", BreakerClosed, state)
+	}
+
+	call(breaker, errTestUpstream)
+	if state := breaker.currentState(); state != BreakerOpen {
+		t.Errorf("Expected state: %s, Got: %s\n", BreakerOpen, state)
+	}
+
+	if err := call(breaker, nil); err != ErrCircuitOpen {
+		t.Errorf("Expected calls to be short-circuited while open, Got: %v\n", err)
+	}
+}
+
+func TestCircuitBreakerShouldOpenOnErrorRate(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		outcomes      []error
+		elapsed       time.Duration
+		expectedState BreakerState
+	}{
+		{
+			scenario:      "Should open once the error rate is reached with enough requests",
+			outcomes:      []error{nil, errTestUpstream, nil, errTestUpstream},
+			expectedState: BreakerOpen,
+		},
+		{
+			scenario:      "Should not open below the minimum number of requests",
+			outcomes:      []error{errTestUpstream, nil, errTestUpstream},
+			expectedState: BreakerClosed,
+		},
+		{
+			scenario:      "Should not open below the error rate",
+			outcomes:      []error{nil, nil, nil, errTestUpstream, nil},
+			expectedState: BreakerClosed,
+		},
+		{
+			scenario:      "Should only consider requests within the window",
+			outcomes:      []error{errTestUpstream, nil, errTestUpstream, nil},
+			elapsed:       30 * time.Second,
+			expectedState: BreakerClosed,
+		},
+	}
+
+	for _, test := range tests {
+
+		clock := &testClock{time.Now()}
+		breaker := newTestBreaker(CircuitBreaker{ErrorRate: 0.5, MinRequests: 4, Window: 60 * time.Second, OpenTimeout: 10 * time.Second}, noopMetrics{}, clock)
+
+		for _, outcome := range test.outcomes {
+			call(breaker, outcome)
+			clock.now = clock.now.Add(test.elapsed)
+		}
+
+		if state := breaker.currentState(); state != test.expectedState {
+			t.Errorf("Scenario '%s'. Expected state: %s, Got: %s\n", test.scenario, test.expectedState, state)
+		}
+	}
+}
+
+func TestCircuitBreakerShouldProbeWhenHalfOpen(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		probeOutcomes []error
+		expectedState BreakerState
+	}{
+		{
+			scenario:      "Should close after successful probes",
+			probeOutcomes: []error{nil, nil},
+			expectedState: BreakerClosed,
+		},
+		{
+			scenario:      "Should open again if a probe fails",
+			probeOutcomes: []error{nil, errTestUpstream},
+			expectedState: BreakerOpen,
+		},
+	}
+
+	for _, test := range tests {
+
+		clock := &testClock{time.Now()}
+		metrics := &testMetrics{}
+		breaker := newTestBreaker(CircuitBreaker{ConsecutiveFailures: 1, OpenTimeout: 10 * time.Second, HalfOpenRequests: 2}, metrics, clock)
+
+		call(breaker, errTestUpstream)
+		clock.now = clock.now.Add(10 * time.Second)
+
+		// Only as many probes as configured are let through
+		firstGeneration, firstErr := breaker.allow()
+		secondGeneration, secondErr := breaker.allow()
+		if _, err := breaker.allow(); err != ErrCircuitOpen {
+			t.Errorf("Scenario '%s'. Expected extra probes to be short-circuited, Got: %v\n", test.scenario, err)
+		}
+		if firstErr != nil || secondErr != nil {
+			t.Errorf("Scenario '%s'. Expected probes to be allowed, Got: %v, %v\n", test.scenario, firstErr, secondErr)
+		}
+		if state := breaker.currentState(); state != BreakerHalfOpen {
+			t.Errorf("Scenario '%s'. Expected state: %s, Got: %s\n", test.scenario, BreakerHalfOpen, state)
+		}
+
+		breaker.record(firstGeneration, test.probeOutcomes[0])
+		breaker.record(secondGeneration, test.probeOutcomes[1])
+
+		if state := breaker.currentState(); state != test.expectedState {
+			t.Errorf("Scenario '%s'. Expected state: %s, Got: %s\n", test.scenario, test.expectedState, state)
+		}
+
+		expectedStates := []int{int(BreakerClosed), int(BreakerOpen), int(BreakerHalfOpen), int(test.expectedState)}
+		if len(metrics.breakerStates) != len(expectedStates) {
+			t.Fatalf("Scenario '%s'. Expected states %v to be exported, Got: %v\n", test.scenario, expectedStates, metrics.breakerStates)
+		}
+		for i := range expectedStates {
+			if metrics.breakerStates[i] != expectedStates[i] {
+				t.Errorf("Scenario '%s'. Expected states %v to be exported, Got: %v\n", test.scenario, expectedStates, metrics.breakerStates)
+				break
+			}
+		}
+	}
+}
+
+func TestCircuitBreakerShouldIgnoreOutcomesFromPreviousStates(t *testing.T) {
+
+	clock := &testClock{time.Now()}
+	breaker := newTestBreaker(CircuitBreaker{ConsecutiveFailures: 1, OpenTimeout: 10 * time.Second}, noopMetrics{}, clock)
+
+	// A slow request allowed while closed finishes once the breaker is half-open
+	slowGeneration, _ := breaker.allow()
+	call(breaker, errTestUpstream)
+	clock.now = clock.now.Add(10 * time.Second)
+	probeGeneration, _ := breaker.allow()
+
+	breaker.record(slowGeneration, errTestUpstream)
+	if state := breaker.currentState(); state != BreakerHalfOpen {
+		t.Errorf("Expected state: %s, Got: %s\n", BreakerHalfOpen, state)
+	}
+
+	breaker.record(probeGeneration, nil)
+	if state := breaker.currentState(); state != BreakerClosed {
+		t.Errorf("Expected state: %s, Got: %s\n", BreakerClosed, state)
+	}
+}
+
+func TestIsSecurePasswordShouldShortCircuitWhenBreakerIsOpen(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.SetFailureRate(1, http.StatusServiceUnavailable)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	breaker := newCircuitBreaker(CircuitBreaker{ConsecutiveFailures: 2, OpenTimeout: 60 * time.Second}, noopMetrics{})
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", breaker: breaker}
+
+	for i := 0; i < 5; i++ {
+		_, err := pwned.IsSecurePassword(context.Background(), "Passw0rd")
+		if err == nil {
+			t.Error("Was expecting an error")
+		}
+		if i >= 2 && err != ErrCircuitOpen {
+			t.Errorf("Expected request %d to be short-circuited, Got: %v\n", i, err)
+		}
+	}
+
+	if requests := fake.Requests(); requests != 2 {
+		t.Errorf("Expected %d requests to pwned service, Got: %d\n", 2, requests)
+	}
+}
@@ -0,0 +1,429 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultChunkRecords  = 1000000
+	defaultProgressEvery = 10000000
+	rangePrefixLength    = 5
+)
+
+type (
+	// DatasetBuilder builds the binary index read by the offline mode out of
+	// any number of HASH:COUNT text files, in any order, or directories of range
+	// files (one file per 5 character prefix holding SUFFIX:COUNT lines, as
+	// returned by the pwned service). Records are sorted in chunks of
+	// ChunkRecords which are spilled to TempDir and merged, so inputs don't need
+	// to fit in memory. Duplicated hashes keep their highest count.
+	DatasetBuilder struct {
+		ChunkRecords  int
+		ProgressEvery int64
+		TempDir       string
+		Logf          func(format string, args ...interface{})
+	}
+
+	// DatasetManifest describes a built dataset, so it can be verified before
+	// being deployed.
+	DatasetManifest struct {
+		SHA256     string                 `json:"sha256"`
+		Records    int64                  `json:"records"`
+		Duplicates int64                  `json:"duplicates"`
+		HashSize   int                    `json:"hashSize"`
+		Inputs     []DatasetManifestInput `json:"inputs"`
+	}
+
+	DatasetManifestInput struct {
+		Path    string `json:"path"`
+		SHA256  string `json:"sha256"`
+		Records int64  `json:"records"`
+	}
+
+	datasetRecord struct {
+		hash  []byte
+		count uint32
+	}
+
+	datasetBuild struct {
+		builder  *DatasetBuilder
+		tempDir  string
+		chunk    []datasetRecord
+		chunks   []string
+		hashSize int
+		read     int64
+		manifest DatasetManifest
+	}
+
+	chunkReader struct {
+		reader *bufio.Reader
+		file   *os.File
+		record datasetRecord
+	}
+
+	chunkHeap []*chunkReader
+)
+
+// Build ingests inputs and writes the dataset index to w.
+func (b *DatasetBuilder) Build(w io.Writer, inputs []string) (*DatasetManifest, error) {
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("at least one input is required")
+	}
+
+	tempDir, err := os.MkdirTemp(b.TempDir, "pwned-dataset-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	build := &datasetBuild{builder: b, tempDir: tempDir}
+	for _, input := range inputs {
+		if err := build.ingest(input); err != nil {
+			return nil, err
+		}
+	}
+	if err := build.spill(); err != nil {
+		return nil, err
+	}
+	if build.read == 0 {
+		return nil, fmt.Errorf("inputs do not contain any hash")
+	}
+
+	b.logf("Read %d records, merging %d sorted chunks", build.read, len(build.chunks))
+	if err := build.merge(w); err != nil {
+		return nil, err
+	}
+
+	b.logf("Wrote %d records (%d duplicates dropped)", build.manifest.Records, build.manifest.Duplicates)
+	return &build.manifest, nil
+}
+
+// BuildFile writes the dataset index to path, replacing it only once it has
+// been completely built.
+func (b *DatasetBuilder) BuildFile(path string, inputs []string) (*DatasetManifest, error) {
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+
+	manifest, err := b.Build(temp, inputs)
+	if err != nil {
+		temp.Close()
+		return nil, err
+	}
+	if err := temp.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, os.Rename(temp.Name(), path)
+}
+
+func (b *DatasetBuilder) logf(format string, args ...interface{}) {
+	if b.Logf != nil {
+		b.Logf(format, args...)
+	}
+}
+
+func (b *DatasetBuilder) chunkRecords() int {
+	if b.ChunkRecords > 0 {
+		return b.ChunkRecords
+	}
+	return defaultChunkRecords
+}
+
+func (b *DatasetBuilder) progressEvery() int64 {
+	if b.ProgressEvery > 0 {
+		return b.ProgressEvery
+	}
+	return defaultProgressEvery
+}
+
+func (db *datasetBuild) ingest(input string) error {
+
+	info, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return db.ingestFile(input, "")
+	}
+
+	entries, err := os.ReadDir(input)
+	if err != nil {
+		return err
+	}
+
+	db.builder.logf("Reading range files from '%s'", input)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		prefix := strings.ToUpper(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if _, err := hex.DecodeString(prefix + "0"); err != nil || len(prefix) != rangePrefixLength {
+			return fmt.Errorf("'%s' is not named after a %d character hash prefix", filepath.Join(input, entry.Name()), rangePrefixLength)
+		}
+
+		if err := db.ingestFile(filepath.Join(input, entry.Name()), prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ingestFile reads HASH:COUNT lines, or SUFFIX:COUNT lines when prefix is set.
+func (db *datasetBuild) ingestFile(path, prefix string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if prefix == "" {
+		db.builder.logf("Reading '%s'", path)
+	}
+
+	checksum := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(file, checksum))
+	var records int64
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		hash, count, err := parseDatasetLine(prefix + line)
+		if err != nil {
+			return fmt.Errorf("'%s' line %d: %w", path, lineNumber, err)
+		}
+		// Padding entries added by the pwned service
+		if count == 0 {
+			continue
+		}
+
+		if db.hashSize == 0 {
+			db.hashSize = len(hash)
+		}
+		if len(hash) != db.hashSize {
+			return fmt.Errorf("'%s' line %d: expected a hash of %d bytes, got %d", path, lineNumber, db.hashSize, len(hash))
+		}
+
+		if err := db.add(datasetRecord{hash: hash, count: count}); err != nil {
+			return err
+		}
+		records++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("'%s': %w", path, err)
+	}
+
+	db.manifest.Inputs = append(db.manifest.Inputs, DatasetManifestInput{
+		Path:    path,
+		SHA256:  hex.EncodeToString(checksum.Sum(nil)),
+		Records: records,
+	})
+	return nil
+}
+
+func (db *datasetBuild) add(record datasetRecord) error {
+
+	db.chunk = append(db.chunk, record)
+	db.read++
+	if db.read%db.builder.progressEvery() == 0 {
+		db.builder.logf("Read %d records", db.read)
+	}
+
+	if len(db.chunk) >= db.builder.chunkRecords() {
+		return db.spill()
+	}
+	return nil
+}
+
+// spill sorts the records held in memory and writes them to a chunk file.
+func (db *datasetBuild) spill() error {
+
+	if len(db.chunk) == 0 {
+		return nil
+	}
+
+	sort.Slice(db.chunk, func(i, j int) bool {
+		return bytes.Compare(db.chunk[i].hash, db.chunk[j].hash) < 0
+	})
+
+	file, err := os.CreateTemp(db.tempDir, "chunk-")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, record := range db.chunk {
+		if err := writeDatasetRecord(writer, record.hash, record.count); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	db.chunks = append(db.chunks, file.Name())
+	db.chunk = db.chunk[:0]
+	return nil
+}
+
+// merge combines the sorted chunks into the index, dropping duplicated hashes.
+func (db *datasetBuild) merge(w io.Writer) error {
+
+	checksum := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(w, checksum))
+	if err := writeDatasetHeader(writer, db.hashSize); err != nil {
+		return err
+	}
+
+	chunks := make(chunkHeap, 0, len(db.chunks))
+	defer func() {
+		for _, chunk := range chunks {
+			chunk.file.Close()
+		}
+	}()
+	for _, path := range db.chunks {
+		chunk, err := openChunk(path, db.hashSize)
+		if err != nil {
+			return err
+		}
+		if chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+	heap.Init(&chunks)
+
+	var pending *datasetRecord
+	for chunks.Len() > 0 {
+		chunk := chunks[0]
+		record := datasetRecord{hash: append([]byte(nil), chunk.record.hash...), count: chunk.record.count}
+
+		more, err := chunk.next()
+		if err != nil {
+			return err
+		}
+		if more {
+			heap.Fix(&chunks, 0)
+		} else {
+			chunk.file.Close()
+			heap.Pop(&chunks)
+		}
+
+		if pending != nil && bytes.Equal(pending.hash, record.hash) {
+			db.manifest.Duplicates++
+			if record.count > pending.count {
+				pending.count = record.count
+			}
+			continue
+		}
+		if err := db.write(writer, pending); err != nil {
+			return err
+		}
+		pending = &record
+	}
+	if err := db.write(writer, pending); err != nil {
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	db.manifest.HashSize = db.hashSize
+	db.manifest.SHA256 = hex.EncodeToString(checksum.Sum(nil))
+	return nil
+}
+
+func (db *datasetBuild) write(w io.Writer, record *datasetRecord) error {
+
+	if record == nil {
+		return nil
+	}
+
+	db.manifest.Records++
+	if db.manifest.Records%db.builder.progressEvery() == 0 {
+		db.builder.logf("Wrote %d records", db.manifest.Records)
+	}
+	return writeDatasetRecord(w, record.hash, record.count)
+}
+
+// openChunk returns nil for an empty chunk.
+func openChunk(path string, hashSize int) (*chunkReader, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := &chunkReader{
+		reader: bufio.NewReader(file),
+		file:   file,
+		record: datasetRecord{hash: make([]byte, hashSize)},
+	}
+	more, err := chunk.next()
+	if err != nil || !more {
+		file.Close()
+		return nil, err
+	}
+
+	return chunk, nil
+}
+
+func (cr *chunkReader) next() (bool, error) {
+
+	if _, err := io.ReadFull(cr.reader, cr.record.hash); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, fmt.Errorf("error when reading sorted chunk: %w", err)
+	}
+
+	countBytes := make([]byte, datasetCountSize)
+	if _, err := io.ReadFull(cr.reader, countBytes); err != nil {
+		return false, fmt.Errorf("error when reading sorted chunk: %w", err)
+	}
+	cr.record.count = binary.BigEndian.Uint32(countBytes)
+
+	return true, nil
+}
+
+func (ch chunkHeap) Len() int { return len(ch) }
+
+func (ch chunkHeap) Less(i, j int) bool {
+	return bytes.Compare(ch[i].record.hash, ch[j].record.hash) < 0
+}
+
+func (ch chunkHeap) Swap(i, j int) { ch[i], ch[j] = ch[j], ch[i] }
+
+func (ch *chunkHeap) Push(x interface{}) {
+	*ch = append(*ch, x.(*chunkReader))
+}
+
+func (ch *chunkHeap) Pop() interface{} {
+	old := *ch
+	last := old[len(old)-1]
+	*ch = old[:len(old)-1]
+	return last
+}
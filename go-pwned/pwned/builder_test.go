@@ -0,0 +1,171 @@
+package pwned
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("could not write test file: %s", err)
+	}
+	return path
+}
+
+func TestDatasetBuilderShouldSortAndDeduplicateInputs(t *testing.T) {
+
+	dir := t.TempDir()
+	first := writeTestFile(t, dir, "first.txt", "E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:42\n"+
+		"0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n"+
+		"6BDF06F4A8AEEA5534ECC2651EADD90751DF6724:1\n")
+	second := writeTestFile(t, dir, "second.txt", "52417CB061186A45A9D36B636D126D79A83B0A37:3\n"+
+		"\n"+
+		"0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:20\n"+
+		"E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:40\n")
+
+	output := filepath.Join(dir, "pwned.idx")
+	builder := &DatasetBuilder{ChunkRecords: 2, TempDir: dir}
+	manifest, err := builder.BuildFile(output, []string{first, second})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	if manifest.Records != 4 || manifest.Duplicates != 2 || manifest.HashSize != 20 {
+		t.Errorf("Expected 4 records, 2 duplicates and 20 bytes hashes, Got: %+v\n", manifest)
+	}
+	if len(manifest.Inputs) != 2 || manifest.Inputs[0].Records != 3 || manifest.Inputs[1].Records != 3 {
+		t.Errorf("Expected 2 inputs of 3 records each, Got: %+v\n", manifest.Inputs)
+	}
+
+	content, _ := os.ReadFile(output)
+	checksum := sha256.Sum256(content)
+	if manifest.SHA256 != hex.EncodeToString(checksum[:]) {
+		t.Errorf("Expected checksum '%x', Got: '%s'\n", checksum, manifest.SHA256)
+	}
+
+	dataset, err := OpenDataset(output)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	tests := []struct {
+		scenario      string
+		hash          string
+		expectedCount int
+	}{
+		{
+			scenario:      "Should keep the highest count of a duplicated hash",
+			hash:          "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76",
+			expectedCount: 20,
+		},
+		{
+			scenario:      "Should keep the highest count when it comes first",
+			hash:          "E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D",
+			expectedCount: 42,
+		},
+		{
+			scenario:      "Should keep hashes found in a single input",
+			hash:          "52417CB061186A45A9D36B636D126D79A83B0A37",
+			expectedCount: 3,
+		},
+	}
+
+	for _, test := range tests {
+		count, found, err := dataset.Lookup(test.hash)
+		if err != nil || !found || count != test.expectedCount {
+			t.Errorf("Scenario '%s'. Expected %d occurrences, Got: %d (found %t, error '%v')\n", test.scenario, test.expectedCount, count, found, err)
+		}
+	}
+}
+
+func TestDatasetBuilderShouldReadRangeDirectories(t *testing.T) {
+
+	dir := t.TempDir()
+	ranges := filepath.Join(dir, "ranges")
+	os.Mkdir(ranges, 0700)
+	writeTestFile(t, ranges, "EBFC7.txt", "910077770C8340F63CD2DCA2AC1F120444F:7\r\n00000000000000000000000000000000000:0\n")
+	writeTestFile(t, ranges, "0ed48", "61207CB4977098BBC60DFFDDF7B3A2CDB76:12\n")
+
+	output := filepath.Join(dir, "pwned.idx")
+	if _, err := (&DatasetBuilder{}).BuildFile(output, []string{ranges}); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	dataset, err := OpenDataset(output)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	if dataset.Len() != 2 {
+		t.Errorf("Expected padding entries to be skipped, Got: %d records\n", dataset.Len())
+	}
+	if count, _, _ := dataset.Lookup("EBFC7910077770C8340F63CD2DCA2AC1F120444F"); count != 7 {
+		t.Errorf("Expected 7 occurrences, Got: %d\n", count)
+	}
+	if count, _, _ := dataset.Lookup("0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76"); count != 12 {
+		t.Errorf("Expected 12 occurrences, Got: %d\n", count)
+	}
+}
+
+func TestDatasetBuilderShouldRejectInvalidInputs(t *testing.T) {
+
+	dir := t.TempDir()
+	ranges := filepath.Join(dir, "ranges")
+	os.Mkdir(ranges, 0700)
+	writeTestFile(t, ranges, "not-a-prefix.txt", "910077770C8340F63CD2DCA2AC1F120444F:7\n")
+
+	tests := []struct {
+		scenario string
+		inputs   []string
+	}{
+		{
+			scenario: "Should fail without inputs",
+			inputs:   nil,
+		},
+		{
+			scenario: "Should fail for missing input",
+			inputs:   []string{filepath.Join(dir, "missing.txt")},
+		},
+		{
+			scenario: "Should fail for malformed line",
+			inputs:   []string{writeTestFile(t, dir, "malformed.txt", "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76\n")},
+		},
+		{
+			scenario: "Should fail for invalid count",
+			inputs:   []string{writeTestFile(t, dir, "count.txt", "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:many\n")},
+		},
+		{
+			scenario: "Should fail for mixed hash sizes",
+			inputs: []string{
+				writeTestFile(t, dir, "sha1.txt", "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n"),
+				writeTestFile(t, dir, "ntlm.txt", "8846F7EAEE8FB117AD06BDD830B7586C:3\n"),
+			},
+		},
+		{
+			scenario: "Should fail for range file not named after a prefix",
+			inputs:   []string{ranges},
+		},
+		{
+			scenario: "Should fail for inputs without hashes",
+			inputs:   []string{writeTestFile(t, dir, "empty.txt", "\n")},
+		},
+	}
+
+	for _, test := range tests {
+		output := filepath.Join(dir, "pwned.idx")
+		if _, err := (&DatasetBuilder{}).BuildFile(output, test.inputs); err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("Scenario '%s'. Expected no dataset to be written\n", test.scenario)
+		}
+	}
+}
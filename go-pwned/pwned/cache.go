@@ -0,0 +1,102 @@
+package pwned
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type (
+	Cache struct {
+		Enabled    bool          `yaml:"enabled"`
+		TTL        time.Duration `yaml:"ttlSeconds"`
+		MaxEntries int           `yaml:"maxEntries"`
+	}
+
+	// rangeCache is a LRU cache of parsed range responses keyed on the hash
+	// prefix. Entries expire once their TTL has elapsed.
+	rangeCache struct {
+		mutex      sync.Mutex
+		ttl        time.Duration
+		maxEntries int
+		entries    map[string]*list.Element
+		order      *list.List
+		metrics    MetricService
+		now        func() time.Time
+	}
+
+	cacheEntry struct {
+		prefix    string
+		suffixes  rangeSuffixes
+		expiresAt time.Time
+	}
+)
+
+func newRangeCache(ttl time.Duration, maxEntries int, metrics MetricService) *rangeCache {
+	return &rangeCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		metrics:    metrics,
+		now:        time.Now,
+	}
+}
+
+func (rc *rangeCache) get(prefix string) (rangeSuffixes, bool) {
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	element, ok := rc.entries[prefix]
+	if !ok {
+		rc.metrics.CacheMiss()
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !rc.now().Before(entry.expiresAt) {
+		rc.remove(element)
+		rc.metrics.CacheMiss()
+		return nil, false
+	}
+
+	rc.order.MoveToFront(element)
+	rc.metrics.CacheHit()
+	return entry.suffixes, true
+}
+
+func (rc *rangeCache) add(prefix string, suffixes rangeSuffixes) {
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	expiresAt := rc.now().Add(rc.ttl)
+	if element, ok := rc.entries[prefix]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.suffixes = suffixes
+		entry.expiresAt = expiresAt
+		rc.order.MoveToFront(element)
+		return
+	}
+
+	rc.entries[prefix] = rc.order.PushFront(&cacheEntry{prefix, suffixes, expiresAt})
+
+	// Evict the least recently used entries when over capacity
+	for rc.maxEntries > 0 && rc.order.Len() > rc.maxEntries {
+		rc.remove(rc.order.Back())
+		rc.metrics.CacheEviction()
+	}
+}
+
+func (rc *rangeCache) len() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.order.Len()
+}
+
+func (rc *rangeCache) remove(element *list.Element) {
+	rc.order.Remove(element)
+	delete(rc.entries, element.Value.(*cacheEntry).prefix)
+}
@@ -0,0 +1,104 @@
+package pwned
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func TestRangeCacheShouldEvictLeastRecentlyUsed(t *testing.T) {
+
+	metrics := &testMetrics{}
+	cache := newRangeCache(time.Minute, 2, metrics)
+
+	cache.add("00000", rangeSuffixes{"A": 1})
+	cache.add("11111", rangeSuffixes{"B": 1})
+
+	// Accessing the first prefix makes the second one the least recently used
+	if _, ok := cache.get("00000"); !ok {
+		t.Error("Expected prefix '00000' to be cached")
+	}
+
+	cache.add("22222", rangeSuffixes{"C": 1})
+
+	if _, ok := cache.get("11111"); ok {
+		t.Error("Expected prefix '11111' to be evicted")
+	}
+	if _, ok := cache.get("00000"); !ok {
+		t.Error("Expected prefix '00000' to be cached")
+	}
+	if _, ok := cache.get("22222"); !ok {
+		t.Error("Expected prefix '22222' to be cached")
+	}
+
+	if cache.len() != 2 {
+		t.Errorf("Expected %d entries, Got: %d\n", 2, cache.len())
+	}
+	if metrics.cacheEvictions != 1 {
+		t.Errorf("Expected %d evictions, Got: %d\n", 1, metrics.cacheEvictions)
+	}
+	if metrics.cacheHits != 3 {
+		t.Errorf("Expected %d hits, Got: %d\n", 3, metrics.cacheHits)
+	}
+	if metrics.cacheMisses != 1 {
+		t.Errorf("Expected %d misses, Got: %d\n", 1, metrics.cacheMisses)
+	}
+}
+
+func TestRangeCacheShouldExpireEntries(t *testing.T) {
+
+	metrics := &testMetrics{}
+	cache := newRangeCache(time.Minute, 10, metrics)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	cache.add("00000", rangeSuffixes{"A": 1})
+
+	now = now.Add(59 * time.Second)
+	if _, ok := cache.get("00000"); !ok {
+		t.Error("Expected prefix '00000' to be cached before the TTL elapses")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := cache.get("00000"); ok {
+		t.Error("Expected prefix '00000' to expire once the TTL elapses")
+	}
+
+	if cache.len() != 0 {
+		t.Errorf("Expected expired entries to be removed, Got: %d entries\n", cache.len())
+	}
+	if metrics.cacheMisses != 1 {
+		t.Errorf("Expected %d misses, Got: %d\n", 1, metrics.cacheMisses)
+	}
+}
+
+func TestIsSecurePasswordShouldUseCachedRanges(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	metrics := &testMetrics{}
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", cache: newRangeCache(time.Minute, 10, metrics)}
+
+	for i := 0; i < 3; i++ {
+		isSecure, err := pwned.IsSecurePassword(context.Background(), "Passw0rd")
+		if err != nil {
+			t.Errorf("Wasn't expecting error. Got %v\n", err)
+		}
+		if isSecure {
+			t.Error("Expected password not to be secure")
+		}
+	}
+
+	if requests := fake.Requests(); requests != 1 {
+		t.Errorf("Expected %d request to pwned service, Got: %d\n", 1, requests)
+	}
+	if metrics.cacheHits != 2 || metrics.cacheMisses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, Got: %d hits and %d misses\n", metrics.cacheHits, metrics.cacheMisses)
+	}
+}
@@ -0,0 +1,109 @@
+package pwned
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+type (
+	// HTTPClient configures the client shared by every request made to the
+	// pwned service.
+	HTTPClient struct {
+		MaxIdleConns        int           `yaml:"maxIdleConns"`
+		MaxIdleConnsPerHost int           `yaml:"maxIdleConnsPerHost"`
+		IdleConnTimeout     time.Duration `yaml:"idleConnTimeoutSeconds"`
+		ProxyURL            string        `yaml:"proxyURL"`
+		UserAgent           string        `yaml:"userAgent"`
+		TLS                 TLS           `yaml:"tls"`
+	}
+
+	TLS struct {
+		CAFile             string `yaml:"caFile"`
+		InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+	}
+
+	// userAgentTransport sets the User-Agent header on every request
+	userAgentTransport struct {
+		userAgent string
+		next      http.RoundTripper
+	}
+)
+
+// newHTTPClient builds the client for the pwned service.
+func newHTTPClient(config HTTPClient) (*http.Client, error) {
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url '%s': %w", config.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var roundTripper http.RoundTripper = transport
+	if config.UserAgent != "" {
+		roundTripper = &userAgentTransport{config.UserAgent, transport}
+	}
+
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+func newTLSConfig(config TLS) (*tls.Config, error) {
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle '%s' does not contain any certificate", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func (ut *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", ut.userAgent)
+	return ut.next.RoundTrip(request)
+}
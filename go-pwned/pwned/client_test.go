@@ -0,0 +1,167 @@
+package pwned
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func TestNewHTTPClientShouldSetUserAgent(t *testing.T) {
+
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	client, err := newHTTPClient(HTTPClient{UserAgent: "go-pwned-test"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	response.Body.Close()
+
+	if userAgent != "go-pwned-test" {
+		t.Errorf("Expected User-Agent '%s', Got: '%s'\n", "go-pwned-test", userAgent)
+	}
+}
+
+func TestNewHTTPClientShouldTrustCABundle(t *testing.T) {
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0600); err != nil {
+		t.Fatalf("could not write CA bundle: %s", err)
+	}
+
+	tests := []struct {
+		scenario      string
+		tls           TLS
+		expectedError bool
+	}{
+		{
+			scenario:      "Should not trust an unknown certificate",
+			tls:           TLS{},
+			expectedError: true,
+		},
+		{
+			scenario:      "Should trust a certificate in the CA bundle",
+			tls:           TLS{CAFile: caFile},
+			expectedError: false,
+		},
+		{
+			scenario:      "Should not verify certificates if told so",
+			tls:           TLS{InsecureSkipVerify: true},
+			expectedError: false,
+		},
+	}
+
+	for _, test := range tests {
+
+		client, err := newHTTPClient(HTTPClient{TLS: test.tls})
+		if err != nil {
+			t.Fatalf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+
+		response, err := client.Get(server.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+		if (err != nil) != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error: %t, Got: %v\n", test.scenario, test.expectedError, err)
+		}
+	}
+}
+
+func TestNewHTTPClientShouldUseProxy(t *testing.T) {
+
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+	}))
+	defer proxy.Close()
+
+	client, err := newHTTPClient(HTTPClient{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	response, err := client.Get("http://api.pwnedpasswords.test/range/EBFC7")
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	response.Body.Close()
+
+	if proxiedHost != "api.pwnedpasswords.test" {
+		t.Errorf("Expected request to be proxied to '%s', Got: '%s'\n", "api.pwnedpasswords.test", proxiedHost)
+	}
+}
+
+func TestNewHTTPClientShouldFailForInvalidConfig(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		config   HTTPClient
+	}{
+		{
+			scenario: "Should fail for an invalid proxy url",
+			config:   HTTPClient{ProxyURL: "://proxy"},
+		},
+		{
+			scenario: "Should fail for a missing CA bundle",
+			config:   HTTPClient{TLS: TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		},
+	}
+
+	for _, test := range tests {
+		if _, err := newHTTPClient(test.config); err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+		}
+	}
+}
+
+func TestIsSecurePasswordShouldReuseConnections(t *testing.T) {
+
+	var connections int32
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewUnstartedServer(fake)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := newHTTPClient(HTTPClient{MaxIdleConns: 10, MaxIdleConnsPerHost: 10})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	pwned := (&Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/"}).WithHTTPClient(client)
+	for i := 0; i < 3; i++ {
+		if _, err := pwned.IsSecurePassword(context.Background(), "Passw0rd"); err != nil {
+			t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+		}
+	}
+
+	if connections != 1 {
+		t.Errorf("Expected %d connection to be opened, Got: %d\n", 1, connections)
+	}
+}
@@ -0,0 +1,83 @@
+package pwned
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// flightGroup deduplicates concurrent range requests for the same prefix,
+	// so callers arriving while a request is in flight wait for it and share
+	// its result instead of issuing their own.
+	flightGroup struct {
+		mutex sync.Mutex
+		calls map[string]*flightCall
+	}
+
+	flightCall struct {
+		done     chan struct{}
+		cancel   context.CancelFunc
+		callers  int
+		suffixes rangeSuffixes
+		err      error
+	}
+)
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do returns the result of fetch for prefix, sharing it with the concurrent
+// callers for the same prefix. A caller whose ctx is done stops waiting, and
+// fetch is cancelled once no caller is waiting for it anymore.
+func (fg *flightGroup) do(ctx context.Context, prefix string, fetch func(ctx context.Context) (rangeSuffixes, error)) (rangeSuffixes, error) {
+
+	fg.mutex.Lock()
+	call, ok := fg.calls[prefix]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.Background())
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		fg.calls[prefix] = call
+		go fg.run(callCtx, prefix, call, fetch)
+	}
+	call.callers++
+	fg.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.suffixes, call.err
+	case <-ctx.Done():
+		fg.leave(prefix, call)
+		return nil, ctx.Err()
+	}
+}
+
+func (fg *flightGroup) run(ctx context.Context, prefix string, call *flightCall, fetch func(ctx context.Context) (rangeSuffixes, error)) {
+
+	call.suffixes, call.err = fetch(ctx)
+	call.cancel()
+
+	fg.mutex.Lock()
+	if fg.calls[prefix] == call {
+		delete(fg.calls, prefix)
+	}
+	fg.mutex.Unlock()
+	close(call.done)
+}
+
+// leave cancels the call once its last caller has left, so callers arriving
+// later start a new one.
+func (fg *flightGroup) leave(prefix string, call *flightCall) {
+
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	call.callers--
+	if call.callers > 0 {
+		return
+	}
+	call.cancel()
+	if fg.calls[prefix] == call {
+		delete(fg.calls, prefix)
+	}
+}
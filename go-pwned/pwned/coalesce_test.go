@@ -0,0 +1,222 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+// newBlockingRangeServer returns a fake range API which holds every request
+// until release is closed.
+func newBlockingRangeServer(status int, body string, requests *int32, release chan struct{}) *httptest.Server {
+
+	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		<-release
+		rw.WriteHeader(status)
+		rw.Write([]byte(body))
+	}
+
+	return httptest.NewServer(http.HandlerFunc(serverHandler))
+}
+
+// callers returns how many callers share the in-flight request for a prefix.
+func callers(flights *flightGroup, prefix string) int {
+	flights.mutex.Lock()
+	defer flights.mutex.Unlock()
+
+	if call, ok := flights.calls[prefix]; ok {
+		return call.callers
+	}
+	return 0
+}
+
+func waitForCallers(t *testing.T, flights *flightGroup, prefix string, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for callers(flights, prefix) < expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d callers on prefix '%s', Got: %d\n", expected, prefix, callers(flights, prefix))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIsSecurePasswordShouldCoalesceConcurrentRequests(t *testing.T) {
+
+	tests := []struct {
+		scenario       string
+		status         int
+		expectedSecure bool
+		expectedError  bool
+	}{
+		{
+			scenario:       "Concurrent callers should share the upstream response",
+			status:         http.StatusOK,
+			expectedSecure: false,
+			expectedError:  false,
+		},
+		{
+			scenario:       "Concurrent callers should share the upstream error",
+			status:         http.StatusServiceUnavailable,
+			expectedSecure: false,
+			expectedError:  true,
+		},
+	}
+
+	const callers = 10
+
+	for _, test := range tests {
+
+		var requests int32
+		release := make(chan struct{})
+		server := newBlockingRangeServer(test.status, "910077770C8340F63CD2DCA2AC1F120444F:4\n", &requests, release)
+
+		flights := newFlightGroup()
+		pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/", flights: flights}
+
+		var waitGroup sync.WaitGroup
+		results := make([]bool, callers)
+		errs := make([]error, callers)
+		for i := 0; i < callers; i++ {
+			waitGroup.Add(1)
+			go func(caller int) {
+				defer waitGroup.Done()
+				results[caller], errs[caller] = pwned.IsSecurePassword(context.Background(), "Passw0rd")
+			}(i)
+		}
+
+		// "Passw0rd" has the SHA-1 prefix EBFC7
+		waitForCallers(t, flights, "EBFC7", callers)
+		close(release)
+		waitGroup.Wait()
+		server.Close()
+
+		if requests != 1 {
+			t.Errorf("Scenario '%s'. Expected %d request to pwned service, Got: %d\n", test.scenario, 1, requests)
+		}
+
+		for i := 0; i < callers; i++ {
+			if (errs[i] != nil) != test.expectedError {
+				t.Errorf("Scenario '%s'. Caller %d expected error: %t, Got: %v\n", test.scenario, i, test.expectedError, errs[i])
+			}
+			if results[i] != test.expectedSecure {
+				t.Errorf("Scenario '%s'. Caller %d expected secure: %t, Got: %t\n", test.scenario, i, test.expectedSecure, results[i])
+			}
+		}
+	}
+}
+
+func TestCoalescingShouldNotCacheCompletedRequests(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", flights: newFlightGroup()}
+
+	for i := 0; i < 3; i++ {
+		if _, err := pwned.IsSecurePassword(context.Background(), "Passw0rd"); err != nil {
+			t.Errorf("Wasn't expecting error. Got %v\n", err)
+		}
+	}
+
+	if requests := fake.Requests(); requests != 3 {
+		t.Errorf("Expected %d requests to pwned service, Got: %d\n", 3, requests)
+	}
+}
+
+func TestCoalescingShouldKeepPrefixesApart(t *testing.T) {
+
+	var requests int32
+	release := make(chan struct{})
+	server := newBlockingRangeServer(http.StatusOK, "", &requests, release)
+	defer server.Close()
+
+	flights := newFlightGroup()
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/", flights: flights}
+
+	var waitGroup sync.WaitGroup
+	for _, password := range []string{"Passw0rd", "ThisIsPassword1"} {
+		waitGroup.Add(1)
+		go func(password string) {
+			defer waitGroup.Done()
+			pwned.IsSecurePassword(context.Background(), password)
+		}(password)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&requests) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	waitGroup.Wait()
+
+	if requests != 2 {
+		t.Errorf("Expected %d requests to pwned service, Got: %d\n", 2, requests)
+	}
+}
+
+func TestCoalescingShouldCancelRequestOnceAllCallersLeave(t *testing.T) {
+
+	var requests int32
+	release := make(chan struct{})
+	defer close(release)
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	defer server.Close()
+
+	flights := newFlightGroup()
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/", flights: flights}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for _, ctx := range []context.Context{first, second} {
+		go func(ctx context.Context) {
+			_, err := pwned.IsSecurePassword(ctx, "Passw0rd")
+			errs <- err
+		}(ctx)
+	}
+	waitForCallers(t, flights, "EBFC7", 2)
+
+	cancelFirst()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to be cancelled, Got: '%v'\n", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("Expected the request to go on while a caller waits for it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the second caller to be cancelled, Got: '%v'\n", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the request to be cancelled once all callers left")
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected %d request to pwned service, Got: %d\n", 1, requests)
+	}
+}
@@ -0,0 +1,295 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	datasetMagic      = "PWNIDX"
+	datasetVersion    = 1
+	datasetHeaderSize = 8
+	datasetCountSize  = 4
+	datasetIndexExt   = ".idx"
+)
+
+// Dataset is a read-only breached password index stored on disk. Records have
+// a fixed size (hash bytes followed by a big endian occurrence count) and are
+// sorted by hash, so lookups are a binary search over the file.
+type Dataset struct {
+	file       *os.File
+	hashSize   int
+	recordSize int64
+	records    int64
+}
+
+// OpenDataset opens the dataset found at path. If path is a plain text HIBP
+// export (sorted HASH:COUNT lines), the binary index is built next to it the
+// first time and reused afterwards.
+func OpenDataset(path string) (*Dataset, error) {
+
+	isIndex, err := isDatasetIndex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isIndex {
+		path, err = ensureDatasetIndex(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return openDatasetIndex(path)
+}
+
+func openDatasetIndex(path string) (*Dataset, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, datasetHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not read dataset header: %w", err)
+	}
+
+	if string(header[:len(datasetMagic)]) != datasetMagic || header[6] != datasetVersion {
+		file.Close()
+		return nil, fmt.Errorf("'%s' is not a valid dataset index", path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	hashSize := int(header[7])
+	recordSize := int64(hashSize + datasetCountSize)
+	dataSize := info.Size() - datasetHeaderSize
+	if hashSize == 0 || dataSize%recordSize != 0 {
+		file.Close()
+		return nil, fmt.Errorf("dataset index '%s' is truncated or corrupted", path)
+	}
+
+	return &Dataset{
+		file:       file,
+		hashSize:   hashSize,
+		recordSize: recordSize,
+		records:    dataSize / recordSize,
+	}, nil
+}
+
+// Len returns the number of hashes held in the dataset.
+func (d *Dataset) Len() int64 {
+	return d.records
+}
+
+// Close releases the underlying file.
+func (d *Dataset) Close() error {
+	return d.file.Close()
+}
+
+// Lookup searches the dataset for an hexadecimal encoded hash and returns the
+// number of times it has been seen in breaches.
+func (d *Dataset) Lookup(hash string) (int, bool, error) {
+
+	target, err := hex.DecodeString(hash)
+	if err != nil || len(target) != d.hashSize {
+		return 0, false, fmt.Errorf("invalid hash '%s' for dataset", hash)
+	}
+
+	record := make([]byte, d.recordSize)
+	var readErr error
+	position := sort.Search(int(d.records), func(i int) bool {
+		if readErr != nil {
+			return true
+		}
+		if readErr = d.readRecord(int64(i), record); readErr != nil {
+			return true
+		}
+		return bytes.Compare(record[:d.hashSize], target) >= 0
+	})
+	if readErr != nil {
+		return 0, false, fmt.Errorf("error when reading dataset: %w", readErr)
+	}
+
+	if int64(position) == d.records {
+		return 0, false, nil
+	}
+
+	if err := d.readRecord(int64(position), record); err != nil {
+		return 0, false, fmt.Errorf("error when reading dataset: %w", err)
+	}
+	if !bytes.Equal(record[:d.hashSize], target) {
+		return 0, false, nil
+	}
+
+	return int(binary.BigEndian.Uint32(record[d.hashSize:])), true, nil
+}
+
+func (d *Dataset) readRecord(index int64, record []byte) error {
+	_, err := d.file.ReadAt(record, datasetHeaderSize+index*d.recordSize)
+	return err
+}
+
+// WriteDataset converts a sorted HIBP text export read from r into the binary
+// index format and returns the number of records written.
+func WriteDataset(w io.Writer, r io.Reader) (int64, error) {
+
+	writer := bufio.NewWriter(w)
+	scanner := bufio.NewScanner(r)
+
+	var previous []byte
+	var records int64
+	hashSize := 0
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		hash, count, err := parseDatasetLine(line)
+		if err != nil {
+			return records, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if hashSize == 0 {
+			hashSize = len(hash)
+			if err := writeDatasetHeader(writer, hashSize); err != nil {
+				return records, err
+			}
+		}
+
+		if len(hash) != hashSize {
+			return records, fmt.Errorf("line %d: expected a hash of %d bytes, got %d", lineNumber, hashSize, len(hash))
+		}
+		if previous != nil && bytes.Compare(previous, hash) >= 0 {
+			return records, fmt.Errorf("line %d: hashes are not sorted in ascending order", lineNumber)
+		}
+		previous = hash
+
+		if err := writeDatasetRecord(writer, hash, count); err != nil {
+			return records, err
+		}
+		records++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return records, err
+	}
+	if hashSize == 0 {
+		return records, fmt.Errorf("dataset does not contain any hash")
+	}
+
+	return records, writer.Flush()
+}
+
+func writeDatasetHeader(w io.Writer, hashSize int) error {
+	header := make([]byte, datasetHeaderSize)
+	copy(header, datasetMagic)
+	header[6] = datasetVersion
+	header[7] = byte(hashSize)
+	_, err := w.Write(header)
+	return err
+}
+
+func writeDatasetRecord(w io.Writer, hash []byte, count uint32) error {
+	if _, err := w.Write(hash); err != nil {
+		return err
+	}
+	countBytes := make([]byte, datasetCountSize)
+	binary.BigEndian.PutUint32(countBytes, count)
+	_, err := w.Write(countBytes)
+	return err
+}
+
+func parseDatasetLine(line string) ([]byte, uint32, error) {
+
+	separator := strings.LastIndex(line, ":")
+	if separator < 0 {
+		return nil, 0, fmt.Errorf("malformed line '%s'", line)
+	}
+
+	hash, err := hex.DecodeString(line[:separator])
+	if err != nil || len(hash) == 0 || len(hash) > 255 {
+		return nil, 0, fmt.Errorf("invalid hash '%s'", line[:separator])
+	}
+
+	count, err := strconv.ParseUint(strings.TrimSpace(line[separator+1:]), 10, 32)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid count '%s'", line[separator+1:])
+	}
+
+	return hash, uint32(count), nil
+}
+
+func isDatasetIndex(path string) (bool, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(datasetMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return string(magic) == datasetMagic, nil
+}
+
+// ensureDatasetIndex builds the binary index for a text export unless an index
+// newer than the export already exists, and returns the index path.
+func ensureDatasetIndex(textPath string) (string, error) {
+
+	indexPath := textPath + datasetIndexExt
+
+	textInfo, err := os.Stat(textPath)
+	if err != nil {
+		return "", err
+	}
+	if indexInfo, err := os.Stat(indexPath); err == nil && !indexInfo.ModTime().Before(textInfo.ModTime()) {
+		return indexPath, nil
+	}
+
+	source, err := os.Open(textPath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	temp, err := os.CreateTemp(filepath.Dir(indexPath), "dataset-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := WriteDataset(temp, source); err != nil {
+		temp.Close()
+		return "", fmt.Errorf("could not build dataset index from '%s': %w", textPath, err)
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+
+	return indexPath, os.Rename(temp.Name(), indexPath)
+}
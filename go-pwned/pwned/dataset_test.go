@@ -0,0 +1,228 @@
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDatasetExport = "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n" +
+	"52417CB061186A45A9D36B636D126D79A83B0A37:3\n" +
+	"6BDF06F4A8AEEA5534ECC2651EADD90751DF6724:1\n" +
+	"E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:42\n"
+
+func writeTestDataset(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("could not write test dataset: %s", err)
+	}
+	return path
+}
+
+func TestWriteDatasetShouldRejectInvalidExports(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		export   string
+	}{
+		{
+			scenario: "Should reject an empty export",
+			export:   "",
+		},
+		{
+			scenario: "Should reject unsorted hashes",
+			export: "6BDF06F4A8AEEA5534ECC2651EADD90751DF6724:1\n" +
+				"0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n",
+		},
+		{
+			scenario: "Should reject duplicated hashes",
+			export: "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:1\n" +
+				"0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:12\n",
+		},
+		{
+			scenario: "Should reject lines without a count",
+			export:   "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76\n",
+		},
+		{
+			scenario: "Should reject hashes which are not hexadecimal",
+			export:   "ZZD4861207CB4977098BBC60DFFDDF7B3A2CDB76:1\n",
+		},
+		{
+			scenario: "Should reject hashes with different lengths",
+			export: "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76:1\n" +
+				"6BDF06F4A8AEEA5534ECC2651EADD907:12\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		if _, err := WriteDataset(&buffer, strings.NewReader(test.export)); err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+		}
+	}
+}
+
+func TestOpenDatasetShouldBuildIndexFromTextExport(t *testing.T) {
+
+	path := writeTestDataset(t, testDatasetExport)
+
+	dataset, err := OpenDataset(path)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	if dataset.Len() != 4 {
+		t.Errorf("Expected %d records, Got: %d\n", 4, dataset.Len())
+	}
+
+	if _, err := os.Stat(path + datasetIndexExt); err != nil {
+		t.Errorf("Expected index file to be created next to the export, Got: '%s'\n", err)
+	}
+
+	// The index itself can be opened directly
+	index, err := OpenDataset(path + datasetIndexExt)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error opening the index, Got: '%s'\n", err)
+	}
+	defer index.Close()
+
+	if index.Len() != dataset.Len() {
+		t.Errorf("Expected %d records, Got: %d\n", dataset.Len(), index.Len())
+	}
+}
+
+func TestDatasetLookup(t *testing.T) {
+
+	dataset, err := OpenDataset(writeTestDataset(t, testDatasetExport))
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	tests := []struct {
+		scenario      string
+		hash          string
+		expectedFound bool
+		expectedCount int
+	}{
+		{
+			scenario:      "Should find the first hash",
+			hash:          "0ED4861207CB4977098BBC60DFFDDF7B3A2CDB76",
+			expectedFound: true,
+			expectedCount: 12,
+		},
+		{
+			scenario:      "Should find the last hash",
+			hash:          "E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D",
+			expectedFound: true,
+			expectedCount: 42,
+		},
+		{
+			scenario:      "Should find hashes regardless of case",
+			hash:          "6bdf06f4a8aeea5534ecc2651eadd90751df6724",
+			expectedFound: true,
+			expectedCount: 1,
+		},
+		{
+			scenario:      "Should not find a hash lower than any record",
+			hash:          "0000000000000000000000000000000000000000",
+			expectedFound: false,
+		},
+		{
+			scenario:      "Should not find a hash greater than any record",
+			hash:          "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			expectedFound: false,
+		},
+		{
+			scenario:      "Should not find a hash between records",
+			hash:          "52417CB061186A45A9D36B636D126D79A83B0A38",
+			expectedFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		count, found, err := dataset.Lookup(test.hash)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if found != test.expectedFound {
+			t.Errorf("Scenario '%s'. Expected found: %t, Got: %t\n", test.scenario, test.expectedFound, found)
+		}
+		if count != test.expectedCount {
+			t.Errorf("Scenario '%s'. Expected count: %d, Got: %d\n", test.scenario, test.expectedCount, count)
+		}
+	}
+
+	if _, _, err := dataset.Lookup("0ED486"); err == nil {
+		t.Error("Was expecting an error for a hash of the wrong size")
+	}
+}
+
+func TestIsSecurePasswordInOfflineMode(t *testing.T) {
+
+	dataset, err := OpenDataset(writeTestDataset(t, testDatasetExport))
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	pwned := Pwned{Enabled: true, Mode: ModeOffline, dataset: dataset}
+
+	tests := []struct {
+		scenario       string
+		password       string
+		expectedSecure bool
+	}{
+		{
+			scenario:       "Should return false if password is in the dataset",
+			password:       "ThisIsPassword1",
+			expectedSecure: false,
+		},
+		{
+			scenario:       "Should return true if password is not in the dataset",
+			password:       "Passw0rdSec*",
+			expectedSecure: true,
+		},
+	}
+
+	for _, test := range tests {
+		isSecure, err := pwned.IsSecurePassword(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if isSecure != test.expectedSecure {
+			t.Errorf("Scenario '%s'. Got %t, expected %t\n", test.scenario, isSecure, test.expectedSecure)
+		}
+	}
+
+	notLoaded := Pwned{Enabled: true, Mode: ModeOffline}
+	if _, err := notLoaded.IsSecurePassword(context.Background(), "Passw0rd"); err == nil {
+		t.Error("Was expecting an error if the dataset is not loaded")
+	}
+}
+
+func TestIsSecurePasswordInOfflineModeWithNTLMDataset(t *testing.T) {
+
+	dataset, err := OpenDataset(writeTestDataset(t, "31D6CFE0D16AE931B73C59D7E0C089C0:4\n"+
+		"8846F7EAEE8FB117AD06BDD830B7586C:9\n"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer dataset.Close()
+
+	pwned := Pwned{Enabled: true, Mode: ModeOffline, Hash: HashNTLM, dataset: dataset}
+
+	result, err := pwned.Check(context.Background(), "password")
+	if err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	if result.Secure || result.Occurrences != 9 {
+		t.Errorf("Expected an insecure password seen 9 times, Got: %+v\n", result)
+	}
+}
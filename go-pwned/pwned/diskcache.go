@@ -0,0 +1,300 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	diskCacheMagic = "PWNRNG 1"
+	// Named after the cache so it isn't mistaken for anybody else's, as the
+	// directory may be shared
+	diskCacheTempDir = ".pwned-range-tmp"
+)
+
+type (
+	// DiskCache keeps the range responses of the pwned service in Dir, so they
+	// survive restarts. Responses younger than TTLSeconds are used as they are,
+	// older ones are revalidated with a conditional request. Once the cache
+	// takes more than MaxSizeMB the least recently used responses are removed.
+	DiskCache struct {
+		Enabled bool          `yaml:"enabled"`
+		Dir     string        `yaml:"dir"`
+		MaxSize int64         `yaml:"maxSizeMB"`
+		TTL     time.Duration `yaml:"ttlSeconds"`
+	}
+
+	diskCache struct {
+		mutex   sync.Mutex
+		dir     string
+		maxSize int64
+		ttl     time.Duration
+		size    int64
+		files   map[string]*diskCacheFile
+		now     func() time.Time
+	}
+
+	diskCacheFile struct {
+		size   int64
+		usedAt time.Time
+	}
+
+	// diskCacheEntry is a range response stored on disk along with the
+	// validators needed to revalidate it.
+	diskCacheEntry struct {
+		body         string
+		etag         string
+		lastModified string
+		storedAt     time.Time
+	}
+)
+
+// newDiskCache creates the cache in config.Dir, picking up the responses
+// already in it.
+func newDiskCache(config DiskCache) (*diskCache, error) {
+
+	if config.Dir == "" {
+		return nil, fmt.Errorf("a directory is required for the disk cache")
+	}
+
+	// Leftovers of responses being written when the service stopped
+	tempDir := filepath.Join(config.Dir, diskCacheTempDir)
+	if err := os.RemoveAll(tempDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		return nil, err
+	}
+
+	dc := &diskCache{
+		dir:     config.Dir,
+		maxSize: config.MaxSize,
+		ttl:     config.TTL,
+		files:   make(map[string]*diskCacheFile),
+		now:     time.Now,
+	}
+
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// Other files may live in the directory, and must never be evicted
+		if !entry.Type().IsRegular() || !isDiskCacheKey(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		dc.files[entry.Name()] = &diskCacheFile{size: info.Size(), usedAt: info.ModTime()}
+		dc.size += info.Size()
+	}
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	dc.evict("")
+
+	return dc, nil
+}
+
+// isDiskCacheKey tells whether name is one the cache stores responses as, a
+// hash prefix with the hash appended unless it is SHA-1.
+func isDiskCacheKey(name string) bool {
+
+	prefix := strings.TrimSuffix(name, "."+HashNTLM)
+	if len(prefix) != 5 {
+		return false
+	}
+	for _, char := range prefix {
+		if !('0' <= char && char <= '9' || 'A' <= char && char <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the response stored for key, or nil if there is none.
+func (dc *diskCache) get(key string) (*diskCacheEntry, error) {
+
+	path := filepath.Join(dc.dir, key)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := decodeDiskCacheEntry(content)
+	if err != nil {
+		return nil, fmt.Errorf("cached range '%s' is corrupted: %w", key, err)
+	}
+	entry.storedAt = info.ModTime()
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	if file, ok := dc.files[key]; ok {
+		file.usedAt = dc.now()
+	}
+
+	return entry, nil
+}
+
+// fresh tells whether entry can be used without revalidating it.
+func (dc *diskCache) fresh(entry *diskCacheEntry) bool {
+	return dc.now().Sub(entry.storedAt) < dc.ttl
+}
+
+// put stores entry for key. The response is written to a temporary file which
+// then replaces the previous one, so readers never see a partial response.
+func (dc *diskCache) put(key string, entry *diskCacheEntry) error {
+
+	content := encodeDiskCacheEntry(entry)
+	temp, err := os.CreateTemp(filepath.Join(dc.dir, diskCacheTempDir), key+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if err := os.Rename(temp.Name(), filepath.Join(dc.dir, key)); err != nil {
+		return err
+	}
+
+	if file, ok := dc.files[key]; ok {
+		dc.size -= file.size
+	}
+	dc.files[key] = &diskCacheFile{size: int64(len(content)), usedAt: dc.now()}
+	dc.size += int64(len(content))
+	dc.evict(key)
+
+	return nil
+}
+
+// touch marks the response stored for key as revalidated.
+func (dc *diskCache) touch(key string) error {
+	now := dc.now()
+	return os.Chtimes(filepath.Join(dc.dir, key), now, now)
+}
+
+// evict removes the least recently used responses, other than keep, until the
+// cache fits in its maximum size. It must be called holding the mutex.
+func (dc *diskCache) evict(keep string) {
+
+	if dc.maxSize <= 0 || dc.size <= dc.maxSize {
+		return
+	}
+
+	keys := make([]string, 0, len(dc.files))
+	for key := range dc.files {
+		if key != keep {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return dc.files[keys[i]].usedAt.Before(dc.files[keys[j]].usedAt)
+	})
+
+	for _, key := range keys {
+		if dc.size <= dc.maxSize {
+			return
+		}
+		if err := os.Remove(filepath.Join(dc.dir, key)); err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		dc.size -= dc.files[key].size
+		delete(dc.files, key)
+	}
+}
+
+// conditional makes request only return a new response if the stored one has
+// changed upstream.
+func (e *diskCacheEntry) conditional(request *http.Request) {
+	if e.etag != "" {
+		request.Header.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		request.Header.Set("If-Modified-Since", e.lastModified)
+	}
+}
+
+func encodeDiskCacheEntry(entry *diskCacheEntry) []byte {
+
+	var buffer bytes.Buffer
+	buffer.WriteString(diskCacheMagic + "\n")
+	if entry.etag != "" {
+		buffer.WriteString("ETag: " + entry.etag + "\n")
+	}
+	if entry.lastModified != "" {
+		buffer.WriteString("Last-Modified: " + entry.lastModified + "\n")
+	}
+	buffer.WriteString("\n")
+	buffer.WriteString(entry.body)
+
+	return buffer.Bytes()
+}
+
+func decodeDiskCacheEntry(content []byte) (*diskCacheEntry, error) {
+
+	reader := bufio.NewReader(bytes.NewReader(content))
+	magic, err := reader.ReadString('\n')
+	if err != nil || strings.TrimSpace(magic) != diskCacheMagic {
+		return nil, fmt.Errorf("unknown format")
+	}
+
+	entry := &diskCacheEntry{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("missing end of headers")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		separator := strings.Index(line, ":")
+		if separator < 0 {
+			return nil, fmt.Errorf("malformed header '%s'", line)
+		}
+		value := strings.TrimSpace(line[separator+1:])
+		switch line[:separator] {
+		case "ETag":
+			entry.etag = value
+		case "Last-Modified":
+			entry.lastModified = value
+		}
+	}
+
+	body := new(strings.Builder)
+	if _, err := reader.WriteTo(body); err != nil {
+		return nil, err
+	}
+	entry.body = body.String()
+
+	return entry, nil
+}
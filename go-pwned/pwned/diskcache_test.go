@@ -0,0 +1,216 @@
+package pwned
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func newTestDiskCache(t *testing.T, dir string, ttl time.Duration) *diskCache {
+	t.Helper()
+
+	cache, err := newDiskCache(DiskCache{Enabled: true, Dir: dir, TTL: ttl})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	return cache
+}
+
+func TestDiskCacheShouldKeepResponsesAcrossRestarts(t *testing.T) {
+
+	dir := t.TempDir()
+	cache := newTestDiskCache(t, dir, time.Minute)
+
+	entry := &diskCacheEntry{body: "910077770C8340F63CD2DCA2AC1F120444F:4\n", etag: `W/"0x8DB"`, lastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}
+	if err := cache.put("EBFC7", entry); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	restarted := newTestDiskCache(t, dir, time.Minute)
+	stored, err := restarted.get("EBFC7")
+	if err != nil || stored == nil {
+		t.Fatalf("Expected response to be stored, Got: '%v'\n", err)
+	}
+	if stored.body != entry.body || stored.etag != entry.etag || stored.lastModified != entry.lastModified {
+		t.Errorf("Expected %+v, Got: %+v\n", entry, stored)
+	}
+	if !restarted.fresh(stored) {
+		t.Error("Expected response to be fresh")
+	}
+	if restarted.size != cache.size {
+		t.Errorf("Expected size %d to be picked up, Got: %d\n", cache.size, restarted.size)
+	}
+
+	if missing, err := restarted.get("00000"); missing != nil || err != nil {
+		t.Errorf("Expected no response for missing prefix, Got: %+v, '%v'\n", missing, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "0ED48"), []byte("not a range"), 0600)
+	if _, err := restarted.get("0ED48"); err == nil {
+		t.Error("Was expecting an error for corrupted response")
+	}
+}
+
+func TestDiskCacheShouldEvictLeastRecentlyUsed(t *testing.T) {
+
+	cache := newTestDiskCache(t, t.TempDir(), time.Minute)
+	clock := &testClock{now: time.Now()}
+	cache.now = clock.Now
+
+	body := "910077770C8340F63CD2DCA2AC1F120444F:4\n"
+	entrySize := int64(len(encodeDiskCacheEntry(&diskCacheEntry{body: body})))
+	cache.maxSize = 2 * entrySize
+
+	for _, prefix := range []string{"00000", "11111"} {
+		cache.put(prefix, &diskCacheEntry{body: body})
+		clock.now = clock.now.Add(time.Second)
+	}
+	cache.get("00000")
+	clock.now = clock.now.Add(time.Second)
+	cache.put("22222", &diskCacheEntry{body: body})
+
+	tests := []struct {
+		scenario       string
+		prefix         string
+		expectedStored bool
+	}{
+		{
+			scenario:       "Should keep recently used response",
+			prefix:         "00000",
+			expectedStored: true,
+		},
+		{
+			scenario:       "Should evict least recently used response",
+			prefix:         "11111",
+			expectedStored: false,
+		},
+		{
+			scenario:       "Should keep new response",
+			prefix:         "22222",
+			expectedStored: true,
+		},
+	}
+
+	for _, test := range tests {
+		stored, _ := cache.get(test.prefix)
+		if (stored != nil) != test.expectedStored {
+			t.Errorf("Scenario '%s'. Expected stored %t, Got: %t\n", test.scenario, test.expectedStored, stored != nil)
+		}
+	}
+	if cache.size != cache.maxSize {
+		t.Errorf("Expected size %d, Got: %d\n", cache.maxSize, cache.size)
+	}
+}
+
+func TestDiskCacheShouldNotEvictForeignFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	foreign := []string{"important.db", "notes.txt", "abcde", "EBFC7.sha1"}
+	for _, name := range foreign {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 2*1024*1024), 0600); err != nil {
+			t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "EBFC7.ntlm"), []byte(diskCacheMagic+"\n\n"), 0600)
+
+	cache, err := newDiskCache(DiskCache{Enabled: true, Dir: dir, MaxSize: 1024 * 1024, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	cache.put("00000", &diskCacheEntry{body: strings.Repeat("910077770C8340F63CD2DCA2AC1F120444F:4\n", 40000)})
+
+	for _, name := range foreign {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected foreign file '%s' to be kept, Got: '%s'\n", name, err)
+		}
+	}
+	if len(cache.files) != 1 {
+		t.Errorf("Expected only the stored response to be tracked, Got: %d files\n", len(cache.files))
+	}
+}
+
+func TestDiskCacheShouldHandleConcurrentWrites(t *testing.T) {
+
+	cache := newTestDiskCache(t, t.TempDir(), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.put("EBFC7", &diskCacheEntry{body: "910077770C8340F63CD2DCA2AC1F120444F:4\n"})
+			if stored, err := cache.get("EBFC7"); err != nil || stored == nil {
+				t.Errorf("Expected a complete response, Got: '%v'\n", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(cache.files) != 1 {
+		t.Errorf("Expected 1 file, Got: %d\n", len(cache.files))
+	}
+}
+
+func TestIsSecurePasswordShouldRevalidateDiskCache(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	var conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			atomic.AddInt32(&conditional, 1)
+		}
+		fake.ServeHTTP(rw, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	tests := []struct {
+		scenario            string
+		ttl                 time.Duration
+		expectedRequests    int32
+		expectedConditional int32
+	}{
+		{
+			scenario:            "Should request and store range on a cold cache",
+			ttl:                 time.Minute,
+			expectedRequests:    1,
+			expectedConditional: 0,
+		},
+		{
+			scenario:            "Should use fresh range after a restart",
+			ttl:                 time.Minute,
+			expectedRequests:    1,
+			expectedConditional: 0,
+		},
+		{
+			scenario:            "Should revalidate stale range",
+			ttl:                 0,
+			expectedRequests:    2,
+			expectedConditional: 1,
+		},
+	}
+
+	for _, test := range tests {
+		pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", diskCache: newTestDiskCache(t, dir, test.ttl)}
+		isSecure, err := pwned.IsSecurePassword(context.Background(), "Passw0rd")
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if isSecure {
+			t.Errorf("Scenario '%s'. Expected password not to be secure\n", test.scenario)
+		}
+		if requests := int32(fake.Requests()); requests != test.expectedRequests || conditional != test.expectedConditional {
+			t.Errorf("Scenario '%s'. Expected %d requests (%d conditional), Got: %d (%d)\n", test.scenario, test.expectedRequests, test.expectedConditional, requests, conditional)
+		}
+	}
+}
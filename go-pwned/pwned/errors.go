@@ -0,0 +1,59 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Failures to get a response from the pwned service are classified as one of
+// these errors, which can be told apart with errors.Is while keeping their
+// cause, e.g. a *net.DNSError, reachable with errors.As.
+var (
+	ErrTimeout   = errors.New("timed out")
+	ErrTransport = errors.New("could not be reached")
+	ErrRead      = errors.New("response could not be read")
+)
+
+type (
+	// ErrUpstreamStatus is returned when the pwned service replies with an
+	// unexpected status code.
+	ErrUpstreamStatus struct {
+		Code       int
+		RetryAfter time.Duration
+	}
+
+	upstreamError struct {
+		kind  error
+		cause error
+	}
+)
+
+func (e *ErrUpstreamStatus) Error() string {
+	return fmt.Sprintf("pwned service replied with status %d", e.Code)
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("pwned service %s: %s", e.kind, e.cause)
+}
+
+func (e *upstreamError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *upstreamError) Unwrap() error {
+	return e.cause
+}
+
+// newUpstreamError classifies cause as a timeout, or as kind otherwise.
+func newUpstreamError(kind error, cause error) error {
+
+	var netErr net.Error
+	if errors.Is(cause, context.DeadlineExceeded) || (errors.As(cause, &netErr) && netErr.Timeout()) {
+		kind = ErrTimeout
+	}
+
+	return &upstreamError{kind: kind, cause: cause}
+}
@@ -0,0 +1,80 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func TestIsSecurePasswordShouldReturnTypedErrors(t *testing.T) {
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// Hold the response until the client gives up
+		<-r.Context().Done()
+	}))
+	defer slowServer.Close()
+
+	fake := pwnedtest.NewServer()
+	fake.SetFailureRate(1, http.StatusServiceUnavailable)
+	failingServer := httptest.NewServer(fake)
+	defer failingServer.Close()
+
+	truncatingServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Length", "100")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("0784E50CD59416AFA6E9E22DEBDA9603901:4\n"))
+	}))
+	defer truncatingServer.Close()
+
+	closedServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+
+	tests := []struct {
+		scenario    string
+		pwned       Pwned
+		expectedErr error
+		expectedAs  func(err error) bool
+	}{
+		{
+			scenario:    "Should return ErrTimeout if pwned service is too slow",
+			pwned:       Pwned{Enabled: true, Timeout: 1, URL: slowServer.URL + "/"},
+			expectedErr: ErrTimeout,
+		},
+		{
+			scenario:    "Should return ErrTransport if pwned service can't be reached",
+			pwned:       Pwned{Enabled: true, Timeout: 2, URL: closedServer.URL + "/"},
+			expectedErr: ErrTransport,
+		},
+		{
+			scenario:    "Should return ErrRead if pwned response is truncated",
+			pwned:       Pwned{Enabled: true, Timeout: 2, URL: truncatingServer.URL + "/"},
+			expectedErr: ErrRead,
+		},
+		{
+			scenario: "Should return ErrUpstreamStatus with the status of the pwned service",
+			pwned:    Pwned{Enabled: true, Timeout: 2, URL: failingServer.URL + "/range/"},
+			expectedAs: func(err error) bool {
+				var statusErr *ErrUpstreamStatus
+				return errors.As(err, &statusErr) && statusErr.Code == http.StatusServiceUnavailable
+			},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := test.pwned.IsSecurePassword(context.Background(), "Passw0rd")
+		if err == nil {
+			t.Errorf("Scenario '%s'. Was expecting an error\n", test.scenario)
+			continue
+		}
+		if test.expectedErr != nil && !errors.Is(err, test.expectedErr) {
+			t.Errorf("Scenario '%s'. Expected error '%s', Got: '%s'\n", test.scenario, test.expectedErr, err)
+		}
+		if test.expectedAs != nil && !test.expectedAs(err) {
+			t.Errorf("Scenario '%s'. Got unexpected error: '%s'\n", test.scenario, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrThrottled = errors.New("pwned service requests are throttled")
+
+type (
+	// RateLimit caps the requests made to the pwned service to
+	// RequestsPerSecond, allowing bursts of up to Burst requests. Requests over
+	// the limit wait for up to QueueTimeoutMillis, and fail with ErrThrottled
+	// if they would have to wait longer.
+	RateLimit struct {
+		Enabled           bool          `yaml:"enabled"`
+		RequestsPerSecond float64       `yaml:"requestsPerSecond"`
+		Burst             int           `yaml:"burst"`
+		QueueTimeout      time.Duration `yaml:"queueTimeoutMillis"`
+	}
+
+	// requestFunc makes a request to the pwned service, as requestPwnedService
+	// does.
+	requestFunc func(client *http.Client, request *http.Request, timeout time.Duration, read func(body io.Reader) error) (pwnedResponse, error)
+
+	// rateLimiter is a token bucket refilled at rate tokens per second.
+	rateLimiter struct {
+		mutex        sync.Mutex
+		rate         float64
+		burst        float64
+		tokens       float64
+		last         time.Time
+		queueTimeout time.Duration
+		metrics      MetricService
+		now          func() time.Time
+		sleep        func(ctx context.Context, wait time.Duration) error
+	}
+)
+
+func newRateLimiter(config RateLimit, metrics MetricService) (*rateLimiter, error) {
+
+	if config.RequestsPerSecond <= 0 {
+		return nil, fmt.Errorf("requestsPerSecond must be greater than 0")
+	}
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+
+	limiter := &rateLimiter{
+		rate:         config.RequestsPerSecond,
+		burst:        float64(config.Burst),
+		tokens:       float64(config.Burst),
+		queueTimeout: config.QueueTimeout,
+		metrics:      metrics,
+		now:          time.Now,
+		sleep:        sleepContext,
+	}
+	limiter.last = limiter.now()
+	return limiter, nil
+}
+
+// limit returns send waiting for a token before each call, the wait being
+// part of the timeout budget.
+func (rl *rateLimiter) limit(send requestFunc) requestFunc {
+	return func(client *http.Client, request *http.Request, timeout time.Duration, read func(body io.Reader) error) (pwnedResponse, error) {
+
+		waited, err := rl.wait(request.Context(), timeout)
+		if err != nil {
+			return pwnedResponse{}, err
+		}
+		return send(client, request, timeout-waited, read)
+	}
+}
+
+// wait takes a token, waiting for it if none is left. It fails straight away
+// with ErrThrottled if the wait would exceed the queue timeout or budget.
+func (rl *rateLimiter) wait(ctx context.Context, budget time.Duration) (time.Duration, error) {
+
+	maxWait := rl.queueTimeout
+	if budget < maxWait {
+		maxWait = budget
+	}
+
+	wait, ok := rl.reserve(maxWait)
+	if !ok {
+		rl.metrics.Throttled()
+		return 0, ErrThrottled
+	}
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	if err := rl.sleep(ctx, wait); err != nil {
+		rl.cancel()
+		return 0, newUpstreamError(ErrTransport, err)
+	}
+	return wait, nil
+}
+
+// reserve takes a token, which may only be available after the returned wait,
+// unless the wait exceeds maxWait.
+func (rl *rateLimiter) reserve(maxWait time.Duration) (time.Duration, bool) {
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+
+	// Tokens go below zero while requests are queued waiting for them
+	var wait time.Duration
+	if rl.tokens < 1 {
+		wait = time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+
+	rl.tokens--
+	return wait, true
+}
+
+// cancel gives back a token reserved by a request which won't be made.
+func (rl *rateLimiter) cancel() {
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.tokens++
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+}
@@ -0,0 +1,158 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func newTestLimiter(t *testing.T, config RateLimit, metrics MetricService, clock *testClock, waits *[]time.Duration) *rateLimiter {
+	t.Helper()
+
+	limiter, err := newRateLimiter(config, metrics)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	limiter.now = clock.Now
+	limiter.last = clock.now
+	limiter.sleep = func(ctx context.Context, wait time.Duration) error {
+		*waits = append(*waits, wait)
+		return ctx.Err()
+	}
+	return limiter
+}
+
+func TestNewRateLimiterShouldRequireRate(t *testing.T) {
+
+	if _, err := newRateLimiter(RateLimit{Enabled: true}, noopMetrics{}); err == nil {
+		t.Error("Was expecting an error without requestsPerSecond")
+	}
+}
+
+func TestRateLimiterShouldAllowBursts(t *testing.T) {
+
+	clock := &testClock{time.Now()}
+	metrics := &testMetrics{}
+	var waits []time.Duration
+	limiter := newTestLimiter(t, RateLimit{RequestsPerSecond: 2, Burst: 3}, metrics, clock, &waits)
+
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.wait(context.Background(), time.Second); err != nil {
+			t.Errorf("Expected request %d within the burst to be allowed, Got: %v\n", i, err)
+		}
+	}
+	if _, err := limiter.wait(context.Background(), time.Second); err != ErrThrottled {
+		t.Errorf("Expected requests over the burst to be throttled, Got: %v\n", err)
+	}
+
+	// Half a second gives back a token at 2 requests per second
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if _, err := limiter.wait(context.Background(), time.Second); err != nil {
+		t.Errorf("Expected a refilled token to be allowed, Got: %v\n", err)
+	}
+
+	if len(waits) != 0 || metrics.throttled != 1 {
+		t.Errorf("Expected %d waits and %d throttled request, Got: %v and %d\n", 0, 1, waits, metrics.throttled)
+	}
+}
+
+func TestRateLimiterShouldQueueUpToTimeout(t *testing.T) {
+
+	tests := []struct {
+		scenario          string
+		budget            time.Duration
+		expectedWaits     []time.Duration
+		expectedThrottled int
+	}{
+		{
+			scenario:          "Should queue requests up to the queue timeout",
+			budget:            time.Second,
+			expectedWaits:     []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+			expectedThrottled: 1,
+		},
+		{
+			scenario:          "Should not queue requests beyond their budget",
+			budget:            150 * time.Millisecond,
+			expectedWaits:     []time.Duration{100 * time.Millisecond},
+			expectedThrottled: 2,
+		},
+	}
+
+	for _, test := range tests {
+
+		clock := &testClock{time.Now()}
+		metrics := &testMetrics{}
+		var waits []time.Duration
+		limiter := newTestLimiter(t, RateLimit{RequestsPerSecond: 10, Burst: 1, QueueTimeout: 250 * time.Millisecond}, metrics, clock, &waits)
+
+		for i := 0; i < 4; i++ {
+			limiter.wait(context.Background(), test.budget)
+		}
+
+		if len(waits) != len(test.expectedWaits) {
+			t.Errorf("Scenario '%s'. Expected waits %v, Got: %v\n", test.scenario, test.expectedWaits, waits)
+		} else {
+			for i := range test.expectedWaits {
+				if waits[i] != test.expectedWaits[i] {
+					t.Errorf("Scenario '%s'. Expected waits %v, Got: %v\n", test.scenario, test.expectedWaits, waits)
+					break
+				}
+			}
+		}
+		if metrics.throttled != test.expectedThrottled {
+			t.Errorf("Scenario '%s'. Expected %d throttled requests, Got: %d\n", test.scenario, test.expectedThrottled, metrics.throttled)
+		}
+	}
+}
+
+func TestRateLimiterShouldGiveBackTokensOfCancelledRequests(t *testing.T) {
+
+	clock := &testClock{time.Now()}
+	var waits []time.Duration
+	limiter := newTestLimiter(t, RateLimit{RequestsPerSecond: 10, Burst: 1, QueueTimeout: 150 * time.Millisecond}, noopMetrics{}, clock, &waits)
+
+	limiter.wait(context.Background(), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limiter.wait(ctx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation error, Got: %v\n", err)
+	}
+
+	// The cancelled request doesn't push the next one past the queue timeout
+	if _, err := limiter.wait(context.Background(), time.Second); err != nil {
+		t.Errorf("Wasn't expecting error, Got: %v\n", err)
+	}
+}
+
+func TestIsSecurePasswordShouldNotSendThrottledRequests(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	limiter, _ := newRateLimiter(RateLimit{RequestsPerSecond: 0.001, Burst: 1}, noopMetrics{})
+	breaker := newCircuitBreaker(CircuitBreaker{ConsecutiveFailures: 1, OpenTimeout: 60 * time.Second}, noopMetrics{})
+	retrier := newRetrier(Retry{MaxAttempts: 3, InitialBackoff: 1 * time.Millisecond, MaxBackoff: 1 * time.Millisecond}, noopMetrics{})
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", limiter: limiter, breaker: breaker, retrier: retrier}
+
+	if _, err := pwned.IsSecurePassword(context.Background(), "Passw0rd"); err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := pwned.IsSecurePassword(context.Background(), "Passw0rd"); err != ErrThrottled {
+			t.Errorf("Expected request %d to be throttled, Got: %v\n", i, err)
+		}
+	}
+
+	if requests := fake.Requests(); requests != 1 {
+		t.Errorf("Expected %d request to pwned service, Got: %d\n", 1, requests)
+	}
+	if state := breaker.currentState(); state != BreakerClosed {
+		t.Errorf("Expected throttled requests to leave the breaker %s, Got: %s\n", BreakerClosed, state)
+	}
+}
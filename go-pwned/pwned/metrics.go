@@ -0,0 +1,27 @@
+package pwned
+
+type (
+	// MetricService records the metrics produced while checking passwords
+	// against the pwned service.
+	MetricService interface {
+		CacheHit()
+		CacheMiss()
+		CacheEviction()
+		CircuitBreakerState(state int)
+		Retry(reason string)
+		WarmUpProgress(processed, total int)
+		WarmUpFailure()
+		Throttled()
+	}
+
+	noopMetrics struct{}
+)
+
+func (noopMetrics) CacheHit()                           {}
+func (noopMetrics) CacheMiss()                          {}
+func (noopMetrics) CacheEviction()                      {}
+func (noopMetrics) CircuitBreakerState(state int)       {}
+func (noopMetrics) Retry(reason string)                 {}
+func (noopMetrics) WarmUpProgress(processed, total int) {}
+func (noopMetrics) WarmUpFailure()                      {}
+func (noopMetrics) Throttled()                          {}
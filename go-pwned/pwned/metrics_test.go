@@ -0,0 +1,65 @@
+package pwned
+
+import "sync"
+
+// testMetrics counts the metrics recorded by the pwned client so tests can
+// verify them.
+type testMetrics struct {
+	mutex          sync.Mutex
+	cacheHits      int
+	cacheMisses    int
+	cacheEvictions int
+	breakerStates  []int
+	retries        []string
+	warmUpFailures int
+	warmUpProgress int
+	throttled      int
+}
+
+func (tm *testMetrics) CacheHit() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.cacheHits++
+}
+
+func (tm *testMetrics) CacheMiss() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.cacheMisses++
+}
+
+func (tm *testMetrics) CacheEviction() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.cacheEvictions++
+}
+
+func (tm *testMetrics) CircuitBreakerState(state int) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.breakerStates = append(tm.breakerStates, state)
+}
+
+func (tm *testMetrics) Retry(reason string) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.retries = append(tm.retries, reason)
+}
+
+func (tm *testMetrics) WarmUpProgress(processed, total int) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.warmUpProgress = processed
+}
+
+func (tm *testMetrics) WarmUpFailure() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.warmUpFailures++
+}
+
+func (tm *testMetrics) Throttled() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.throttled++
+}
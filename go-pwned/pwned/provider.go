@@ -0,0 +1,247 @@
+package pwned
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	ProviderBanned  = "banned"
+	ProviderOffline = "offline"
+	ProviderBloom   = "bloom"
+	ProviderRemote  = "remote"
+
+	// FirstHit stops at the first provider finding the password, skipping the
+	// providers which fail as long as another one answers. AllMustPass asks
+	// every provider, and fails the check if any of them fails.
+	FirstHit    Strategy = "firstHit"
+	AllMustPass Strategy = "allMustPass"
+)
+
+type (
+	// Provider is a source of breached passwords. Checks should give up once
+	// ctx is done.
+	Provider interface {
+		Name() string
+		Check(ctx context.Context, password string) (Result, error)
+	}
+
+	Strategy string
+
+	// ProviderConfig declares one of the providers of the chain. Path is the
+	// banned list, dataset or bloom filter to load, depending on Type.
+	ProviderConfig struct {
+		Type    string        `yaml:"type"`
+		Name    string        `yaml:"name"`
+		Path    string        `yaml:"path"`
+		Confirm bool          `yaml:"confirm"`
+		Timeout time.Duration `yaml:"timeoutMillis"`
+	}
+
+	// Chain consults its providers in order, following its strategy. It is a
+	// Provider itself.
+	Chain struct {
+		strategy Strategy
+		links    []chainLink
+	}
+
+	chainLink struct {
+		provider Provider
+		timeout  time.Duration
+	}
+
+	providerFunc struct {
+		name  string
+		check func(ctx context.Context, password string) (Result, error)
+	}
+
+	providerAnswer struct {
+		result Result
+		err    error
+	}
+)
+
+func NewChain(strategy Strategy) (*Chain, error) {
+
+	switch strategy {
+	case "":
+		strategy = FirstHit
+	case FirstHit, AllMustPass:
+	default:
+		return nil, fmt.Errorf("unknown provider strategy '%s'", strategy)
+	}
+
+	return &Chain{strategy: strategy}, nil
+}
+
+// Add appends provider to the chain. Checks taking longer than timeout fail,
+// a zero timeout waits for as long as the provider takes.
+func (c *Chain) Add(provider Provider, timeout time.Duration) {
+	c.links = append(c.links, chainLink{provider: provider, timeout: timeout})
+}
+
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+func (c *Chain) Check(ctx context.Context, password string) (Result, error) {
+
+	if c.strategy == AllMustPass {
+		return c.checkAll(ctx, password)
+	}
+	return c.checkFirstHit(ctx, password)
+}
+
+func (c *Chain) checkFirstHit(ctx context.Context, password string) (Result, error) {
+
+	var firstErr error
+	for _, link := range c.links {
+		result, err := link.check(ctx, password)
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !result.Secure {
+			return result, nil
+		}
+	}
+
+	// A failing provider might have found the password, so it is only
+	// accepted if every provider answered
+	if firstErr != nil {
+		return Result{}, firstErr
+	}
+
+	return Result{Secure: true}, nil
+}
+
+func (c *Chain) checkAll(ctx context.Context, password string) (Result, error) {
+
+	merged := Result{Secure: true}
+	for _, link := range c.links {
+		result, err := link.check(ctx, password)
+		if err != nil {
+			return Result{}, err
+		}
+		merged.Secure = merged.Secure && result.Secure
+		merged.Warning = merged.Warning || result.Warning
+		if result.Occurrences > merged.Occurrences {
+			merged.Occurrences = result.Occurrences
+		}
+	}
+
+	return merged, nil
+}
+
+// check gives up on the provider once its timeout is reached or ctx is done,
+// even if the provider itself doesn't.
+func (cl chainLink) check(ctx context.Context, password string) (Result, error) {
+
+	var linkCtx context.Context
+	var cancel context.CancelFunc
+	if cl.timeout > 0 {
+		linkCtx, cancel = context.WithTimeout(ctx, cl.timeout)
+	} else {
+		linkCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	answers := make(chan providerAnswer, 1)
+	go func() {
+		result, err := cl.provider.Check(linkCtx, password)
+		answers <- providerAnswer{result: result, err: err}
+	}()
+
+	select {
+	case answer := <-answers:
+		return cl.wrap(answer.result, answer.err)
+	case <-linkCtx.Done():
+		if ctx.Err() == nil {
+			return Result{}, fmt.Errorf("provider '%s' %w after %s", cl.provider.Name(), ErrTimeout, cl.timeout)
+		}
+		return cl.wrap(Result{}, ctx.Err())
+	}
+}
+
+func (cl chainLink) wrap(result Result, err error) (Result, error) {
+	if err != nil {
+		return Result{}, fmt.Errorf("provider '%s': %w", cl.provider.Name(), err)
+	}
+	return result, nil
+}
+
+func (pf providerFunc) Name() string {
+	return pf.name
+}
+
+func (pf providerFunc) Check(ctx context.Context, password string) (Result, error) {
+	return pf.check(ctx, password)
+}
+
+// newChain builds the chain declared in the configuration, loading the files
+// its providers need. Providers use p as it is when checking, so later
+// changes such as WithHTTPClient apply to them.
+func (p *Pwned) newChain() (*Chain, error) {
+
+	chain, err := NewChain(p.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range p.Providers {
+		provider, err := p.newProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		chain.Add(provider, config.Timeout)
+	}
+
+	return chain, nil
+}
+
+func (p *Pwned) newProvider(config ProviderConfig) (Provider, error) {
+
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	switch config.Type {
+	case ProviderBanned:
+		list, err := OpenBannedList(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load banned list for provider '%s': %w", name, err)
+		}
+		return providerFunc{name: name, check: func(ctx context.Context, password string) (Result, error) {
+			return list.Check(password)
+		}}, nil
+	case ProviderOffline:
+		dataset, err := OpenDataset(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load dataset for provider '%s': %w", name, err)
+		}
+		return providerFunc{name: name, check: func(ctx context.Context, password string) (Result, error) {
+			return p.checkOffline(dataset, password)
+		}}, nil
+	case ProviderBloom:
+		filter, err := OpenBloomFilter(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load bloom filter for provider '%s': %w", name, err)
+		}
+		return providerFunc{name: name, check: func(ctx context.Context, password string) (Result, error) {
+			return p.checkBloom(ctx, filter, config.Confirm, password)
+		}}, nil
+	case ProviderRemote:
+		return providerFunc{name: name, check: func(ctx context.Context, password string) (Result, error) {
+			return p.checkOnline(ctx, password)
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown provider type '%s'", config.Type)
+}
@@ -0,0 +1,291 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+var errTestProvider = errors.New("provider is down")
+
+type testProvider struct {
+	name   string
+	result Result
+	err    error
+	delay  time.Duration
+	calls  int
+}
+
+func (tp *testProvider) Name() string {
+	return tp.name
+}
+
+func (tp *testProvider) Check(ctx context.Context, password string) (Result, error) {
+	tp.calls++
+	time.Sleep(tp.delay)
+	return tp.result, tp.err
+}
+
+func newTestChain(t *testing.T, strategy Strategy, providers ...*testProvider) *Chain {
+	t.Helper()
+
+	chain, err := NewChain(strategy)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	for _, provider := range providers {
+		chain.Add(provider, 0)
+	}
+	return chain
+}
+
+func TestChainWithFirstHitStrategy(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		providers     []*testProvider
+		expected      Result
+		expectedErr   bool
+		expectedCalls []int
+	}{
+		{
+			scenario: "Should stop at the first provider finding the password",
+			providers: []*testProvider{
+				{name: "banned", result: Result{Secure: true}},
+				{name: "offline", result: Result{Secure: false, Occurrences: 12}},
+				{name: "remote", result: Result{Secure: false, Occurrences: 15}},
+			},
+			expected:      Result{Secure: false, Occurrences: 12},
+			expectedCalls: []int{1, 1, 0},
+		},
+		{
+			scenario: "Should accept password if no provider finds it",
+			providers: []*testProvider{
+				{name: "banned", result: Result{Secure: true}},
+				{name: "remote", result: Result{Secure: true}},
+			},
+			expected:      Result{Secure: true},
+			expectedCalls: []int{1, 1},
+		},
+		{
+			scenario: "Should skip failing providers",
+			providers: []*testProvider{
+				{name: "offline", err: errTestProvider},
+				{name: "remote", result: Result{Secure: false, Occurrences: 3}},
+			},
+			expected:      Result{Secure: false, Occurrences: 3},
+			expectedCalls: []int{1, 1},
+		},
+		{
+			scenario: "Should fail if no provider finds the password while others fail",
+			providers: []*testProvider{
+				{name: "banned", result: Result{Secure: true}},
+				{name: "remote", err: errTestProvider},
+			},
+			expectedErr:   true,
+			expectedCalls: []int{1, 1},
+		},
+		{
+			scenario: "Should fail if all providers fail",
+			providers: []*testProvider{
+				{name: "offline", err: errTestProvider},
+				{name: "remote", err: errTestProvider},
+			},
+			expectedErr:   true,
+			expectedCalls: []int{1, 1},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := newTestChain(t, FirstHit, test.providers...).Check(context.Background(), "Passw0rd")
+		if test.expectedErr != (err != nil) {
+			t.Errorf("Scenario '%s'. Expected error %t, Got: '%v'\n", test.scenario, test.expectedErr, err)
+		}
+		if result != test.expected {
+			t.Errorf("Scenario '%s'. Expected %+v, Got: %+v\n", test.scenario, test.expected, result)
+		}
+		for i, provider := range test.providers {
+			if provider.calls != test.expectedCalls[i] {
+				t.Errorf("Scenario '%s'. Expected provider '%s' to be called %d times, Got: %d\n", test.scenario, provider.name, test.expectedCalls[i], provider.calls)
+			}
+		}
+	}
+}
+
+func TestChainWithAllMustPassStrategy(t *testing.T) {
+
+	tests := []struct {
+		scenario    string
+		providers   []*testProvider
+		expected    Result
+		expectedErr bool
+	}{
+		{
+			scenario: "Should accept password if all providers accept it",
+			providers: []*testProvider{
+				{name: "banned", result: Result{Secure: true}},
+				{name: "remote", result: Result{Secure: true, Occurrences: 2, Warning: true}},
+			},
+			expected: Result{Secure: true, Occurrences: 2, Warning: true},
+		},
+		{
+			scenario: "Should reject password if any provider finds it",
+			providers: []*testProvider{
+				{name: "offline", result: Result{Secure: false, Occurrences: 12}},
+				{name: "remote", result: Result{Secure: false, Occurrences: 15}},
+			},
+			expected: Result{Secure: false, Occurrences: 15},
+		},
+		{
+			scenario: "Should fail if any provider fails",
+			providers: []*testProvider{
+				{name: "banned", result: Result{Secure: true}},
+				{name: "remote", err: errTestProvider},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		result, err := newTestChain(t, AllMustPass, test.providers...).Check(context.Background(), "Passw0rd")
+		if test.expectedErr != (err != nil) {
+			t.Errorf("Scenario '%s'. Expected error %t, Got: '%v'\n", test.scenario, test.expectedErr, err)
+		}
+		if result != test.expected {
+			t.Errorf("Scenario '%s'. Expected %+v, Got: %+v\n", test.scenario, test.expected, result)
+		}
+	}
+}
+
+func TestChainShouldTimeoutSlowProviders(t *testing.T) {
+
+	slow := &testProvider{name: "remote", result: Result{Secure: false, Occurrences: 3}, delay: 200 * time.Millisecond}
+	chain, _ := NewChain(AllMustPass)
+	chain.Add(slow, 20*time.Millisecond)
+
+	start := time.Now()
+	_, err := chain.Check(context.Background(), "Passw0rd")
+	if err == nil || !strings.Contains(err.Error(), "'remote' timed out") {
+		t.Errorf("Was expecting a timeout error, Got: '%v'\n", err)
+	}
+	if elapsed := time.Since(start); elapsed >= slow.delay {
+		t.Errorf("Expected the check not to wait for the provider, took %s\n", elapsed)
+	}
+}
+
+func TestNewChainShouldRejectUnknownStrategy(t *testing.T) {
+	if _, err := NewChain("anyHit"); err == nil {
+		t.Error("Was expecting an error for an unknown strategy")
+	}
+}
+
+func TestCheckShouldUseProvidersFromConfig(t *testing.T) {
+
+	bannedPath := filepath.Join(t.TempDir(), "banned.txt")
+	if err := os.WriteFile(bannedPath, []byte("AcmeCorp2022\n"), 0600); err != nil {
+		t.Fatalf("could not write banned list: %s", err)
+	}
+
+	pwned := Pwned{
+		Enabled:  true,
+		Strategy: FirstHit,
+		Providers: []ProviderConfig{
+			{Type: ProviderBanned, Name: "company", Path: bannedPath},
+			{Type: ProviderOffline, Path: writeTestDataset(t, testDatasetExport), Timeout: 100 * time.Millisecond},
+		},
+	}
+
+	chain, err := pwned.newChain()
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	pwned.chain = chain
+
+	tests := []struct {
+		scenario string
+		password string
+		expected Result
+	}{
+		{
+			scenario: "Should reject password in the banned list",
+			password: "AcmeCorp2022",
+			expected: Result{Secure: false},
+		},
+		{
+			scenario: "Should reject password in the dataset",
+			password: "ThisIsPassword1",
+			expected: Result{Secure: false, Occurrences: 12},
+		},
+		{
+			scenario: "Should accept password found by no provider",
+			password: "Passw0rdSec*",
+			expected: Result{Secure: true},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := pwned.Check(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result.Secure != test.expected.Secure || result.Occurrences != test.expected.Occurrences {
+			t.Errorf("Scenario '%s'. Expected %+v, Got: %+v\n", test.scenario, test.expected, result)
+		}
+	}
+
+	pwned.Providers = []ProviderConfig{{Type: "ldap"}}
+	if _, err := pwned.newChain(); err == nil {
+		t.Error("Was expecting an error for an unknown provider type")
+	}
+}
+
+// roundTripperFunc lets a function be the transport of an http.Client.
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (rt roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return rt(r)
+}
+
+func TestChainShouldUseHTTPClientSetAfterCreation(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	pwned := &Pwned{
+		Enabled:   true,
+		Timeout:   2,
+		URL:       server.URL + "/range/",
+		Providers: []ProviderConfig{{Type: ProviderRemote}},
+	}
+
+	chain, err := pwned.newChain()
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	pwned.chain = chain
+
+	var requests int32
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&requests, 1)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	pwned.WithHTTPClient(&http.Client{Transport: transport})
+
+	if _, err := pwned.Check(context.Background(), "Passw0rd"); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected %d request through the client set after creating the chain, Got: %d\n", 1, requests)
+	}
+}
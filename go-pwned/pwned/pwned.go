@@ -3,13 +3,19 @@ package pwned
 import (
 	"context"
 	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/jruben-rg/password-service/go-pwned/config"
+	"github.com/jruben-rg/password-service/go-pwned/password/normalize"
+	"golang.org/x/crypto/md4"
 )
 
 type (
@@ -18,55 +24,548 @@ type (
 	}
 
 	Pwned struct {
-		Enabled bool          `yaml:"enabled"`
-		Timeout time.Duration `yaml:"timeoutSeconds"`
-		URL     string        `yaml:"url"`
+		Enabled     bool          `yaml:"enabled"`
+		Timeout     time.Duration `yaml:"timeoutSeconds"`
+		URL         string        `yaml:"url"`
+		Mode        string        `yaml:"mode"`
+		DatasetPath string        `yaml:"datasetPath"`
+		Bloom       Bloom         `yaml:"bloom"`
+		Cache       Cache         `yaml:"cache"`
+		DiskCache   DiskCache     `yaml:"diskCache"`
+		WarmUp      WarmUp        `yaml:"warmUp"`
+		Coalesce    bool          `yaml:"coalesce"`
+		// Passwords seen fewer than MinOccurrences times are accepted, and
+		// flagged with a warning if seen at least WarnOccurrences times.
+		MinOccurrences  int `yaml:"minOccurrences"`
+		WarnOccurrences int `yaml:"warnOccurrences"`
+		// Padding asks the pwned service to add decoy entries to the responses
+		Padding bool `yaml:"padding"`
+		// Hash selects the hash passwords are looked up by, sha1 or ntlm
+		Hash           string         `yaml:"hash"`
+		CircuitBreaker CircuitBreaker `yaml:"circuitBreaker"`
+		// FailurePolicy decides what to do with a password when it can't be
+		// checked against breached passwords.
+		FailurePolicy FailurePolicy `yaml:"failurePolicy"`
+		Retry         Retry         `yaml:"retry"`
+		RateLimit     RateLimit     `yaml:"rateLimit"`
+		HTTP          HTTPClient    `yaml:"http"`
+		// Providers, when set, replaces Mode by a chain of sources of breached
+		// passwords consulted in order.
+		Providers []ProviderConfig `yaml:"providers"`
+		Strategy  Strategy         `yaml:"strategy"`
+		// Normalize also looks up the selected variants of passwords, as
+		// P@55w0rd for Password, each of them being a lookup of its own.
+		Normalize normalize.Normalization `yaml:"normalize"`
+
+		dataset   *Dataset
+		bloom     *BloomFilter
+		cache     *rangeCache
+		diskCache *diskCache
+		flights   *flightGroup
+		breaker   *circuitBreaker
+		retrier   *retrier
+		limiter   *rateLimiter
+		client    *http.Client
+		chain     *Chain
+		warmer    *warmer
+	}
+
+	FailurePolicy string
+
+	// Result is the outcome of checking a password against breached passwords.
+	Result struct {
+		Secure      bool
+		Occurrences int
+		Warning     bool
+	}
+
+	// pwnedResponse is a successful response of the pwned service, whose body
+	// has already been read.
+	pwnedResponse struct {
+		etag         string
+		lastModified string
+		notModified  bool
 	}
+
+	// rangeSuffixes holds the hash suffixes returned by the pwned service for a
+	// prefix, along with the number of times each one has been seen.
+	rangeSuffixes map[string]int
+)
+
+const (
+	ModeOnline  = "online"
+	ModeOffline = "offline"
+	ModeBloom   = "bloom"
+
+	HashSHA1 = "sha1"
+	HashNTLM = "ntlm"
+
+	// FailClosed rejects the password, FailOpen accepts it and
+	// FailOpenWithWarning accepts it flagging that the check was skipped.
+	FailClosed          FailurePolicy = "closed"
+	FailOpen            FailurePolicy = "open"
+	FailOpenWithWarning FailurePolicy = "open-with-warning"
 )
 
-func NewPwnedConfig(filePath string) *Pwned {
+func NewPwnedConfig(filePath string, metrics MetricService) *Pwned {
 	pwnedConfig := &PwnedConfig{}
 	err := config.Read(filePath, &pwnedConfig)
 	if err != nil {
 		panic("Could not read configuration for Pwned endpoint")
 	}
-	return &pwnedConfig.Pwned
+
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+
+	pwned := &pwnedConfig.Pwned
+	switch pwned.FailurePolicy {
+	case "", FailClosed, FailOpen, FailOpenWithWarning:
+	default:
+		panic(fmt.Sprintf("Unknown failure policy '%s' for Pwned", pwned.FailurePolicy))
+	}
+
+	switch pwned.Mode {
+	case "", ModeOnline, ModeOffline, ModeBloom:
+	default:
+		panic(fmt.Sprintf("Unknown mode '%s' for Pwned", pwned.Mode))
+	}
+
+	switch pwned.Hash {
+	case "", HashSHA1, HashNTLM:
+	default:
+		panic(fmt.Sprintf("Unknown hash '%s' for Pwned", pwned.Hash))
+	}
+
+	pwned.convertUnits()
+
+	pwned.client, err = newHTTPClient(pwned.HTTP)
+	if err != nil {
+		panic(fmt.Sprintf("Could not create http client for Pwned: %s", err))
+	}
+
+	if pwned.Cache.Enabled {
+		pwned.cache = newRangeCache(pwned.Cache.TTL, pwned.Cache.MaxEntries, metrics)
+	}
+
+	if pwned.DiskCache.Enabled {
+		pwned.diskCache, err = newDiskCache(pwned.DiskCache)
+		if err != nil {
+			panic(fmt.Sprintf("Could not create disk cache for Pwned: %s", err))
+		}
+	}
+
+	if pwned.Coalesce {
+		pwned.flights = newFlightGroup()
+	}
+
+	if pwned.CircuitBreaker.Enabled {
+		pwned.breaker = newCircuitBreaker(pwned.CircuitBreaker, metrics)
+	}
+
+	if pwned.Retry.MaxAttempts > 1 {
+		pwned.retrier = newRetrier(pwned.Retry, metrics)
+	}
+
+	if pwned.RateLimit.Enabled {
+		pwned.limiter, err = newRateLimiter(pwned.RateLimit, metrics)
+		if err != nil {
+			panic(fmt.Sprintf("Could not create rate limit for Pwned: %s", err))
+		}
+	}
+
+	if pwned.Enabled && pwned.Mode == ModeOffline {
+		pwned.dataset, err = OpenDataset(pwned.DatasetPath)
+		if err != nil {
+			panic(fmt.Sprintf("Could not load offline dataset for Pwned: %s", err))
+		}
+	}
+
+	if pwned.Enabled && pwned.Mode == ModeBloom {
+		pwned.bloom, err = OpenBloomFilter(pwned.Bloom.Path)
+		if err != nil {
+			panic(fmt.Sprintf("Could not load bloom filter for Pwned: %s", err))
+		}
+	}
+
+	if pwned.Enabled && len(pwned.Providers) > 0 {
+		pwned.chain, err = pwned.newChain()
+		if err != nil {
+			panic(fmt.Sprintf("Could not create providers for Pwned: %s", err))
+		}
+	}
+
+	if pwned.Enabled && pwned.WarmUp.Enabled {
+		if pwned.cache == nil && pwned.diskCache == nil {
+			panic("Could not create warm-up for Pwned: it needs cache or diskCache enabled")
+		}
+		pwned.warmer, err = newWarmer(pwned.WarmUp, metrics)
+		if err != nil {
+			panic(fmt.Sprintf("Could not create warm-up for Pwned: %s", err))
+		}
+	}
+
+	return pwned
+}
+
+// convertUnits turns the durations and sizes of the configuration, read from
+// yaml in the units named by their keys, into durations and bytes, so they are
+// ready to use. Timeout is left in seconds, as requests convert it.
+func (p *Pwned) convertUnits() {
+	p.Cache.TTL *= time.Second
+	p.CircuitBreaker.Window *= time.Second
+	p.CircuitBreaker.OpenTimeout *= time.Second
+	p.Retry.InitialBackoff *= time.Millisecond
+	p.Retry.MaxBackoff *= time.Millisecond
+	p.HTTP.IdleConnTimeout *= time.Second
+	p.DiskCache.MaxSize *= 1024 * 1024
+	p.DiskCache.TTL *= time.Second
+	p.RateLimit.QueueTimeout *= time.Millisecond
+	p.WarmUp.Refresh *= time.Second
+	p.WarmUp.ReadyTimeout *= time.Second
+	for i := range p.Providers {
+		p.Providers[i].Timeout *= time.Millisecond
+	}
+}
+
+// StartWarmUp fetches the warm-up prefixes into the caches in the background,
+// until ctx is done.
+func (p Pwned) StartWarmUp(ctx context.Context) {
+
+	if p.warmer == nil {
+		return
+	}
+
+	go p.warmer.run(ctx, func(ctx context.Context, prefix string) error {
+		_, err := p.fetchRange(ctx, prefix)
+		return err
+	})
+}
+
+// Ready tells whether the warm-up, if any, has fetched enough prefixes to
+// serve validations without waiting on the pwned service.
+func (p Pwned) Ready() bool {
+	return p.warmer == nil || p.warmer.isReady()
 }
 
 func (p Pwned) IsEnabled() bool {
 	return p.Enabled
 }
 
-func (p Pwned) IsSecurePassword(password string) (bool, error) {
+// WithHTTPClient replaces the client used to reach the pwned service.
+func (p *Pwned) WithHTTPClient(client *http.Client) *Pwned {
+	p.client = client
+	return p
+}
+
+func (p Pwned) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	return http.DefaultClient
+}
+
+func (p Pwned) IsSecurePassword(ctx context.Context, password string) (bool, error) {
 
-	if p.Enabled {
+	result, err := p.Check(ctx, password)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Secure, nil
+}
+
+// Check looks the password up in breached passwords and reports how many
+// times it has been seen, along with whether it is considered secure.
+// Cancelling ctx, or reaching its deadline, aborts the requests made to the
+// pwned service.
+func (p Pwned) Check(ctx context.Context, password string) (Result, error) {
+
+	if !p.Enabled {
+		return Result{Secure: true}, nil
+	}
 
-		// Encode password
-		encodedPassword := encodeToSHA1(password)
-		passwordPrefix := encodedPassword[:5]
-		passwordSuffix := encodedPassword[5:]
+	if !p.Normalize.Enabled() {
+		return p.checkPassword(ctx, password)
+	}
 
-		// Create request to pwned service
-		pwnedRequest, err := http.NewRequest(http.MethodGet, p.URL+passwordPrefix, nil)
+	// The password is insecure if any of its variants is, and otherwise as
+	// seen as the most seen of them
+	var worst Result
+	for i, variant := range p.Normalize.Variants(password) {
+		result, err := p.checkPassword(ctx, variant)
 		if err != nil {
-			return false, fmt.Errorf("could not create http request to pwned service")
+			return Result{}, err
+		}
+		if !result.Secure {
+			return result, nil
 		}
+		if i == 0 || result.Occurrences > worst.Occurrences {
+			worst = result
+		}
+	}
+
+	return worst, nil
+}
+
+func (p Pwned) checkPassword(ctx context.Context, password string) (Result, error) {
+
+	if p.chain != nil {
+		return p.chain.Check(ctx, password)
+	}
+
+	switch p.Mode {
+	case ModeOffline:
+		return p.checkOffline(p.dataset, password)
+	case ModeBloom:
+		return p.checkBloom(ctx, p.bloom, p.Bloom.Confirm, password)
+	}
 
-		// Execute request to pwned service
-		client := http.Client{}
-		responseBody, err := requestPwnedService(&client, pwnedRequest, p.Timeout*time.Second)
+	return p.checkOnline(ctx, password)
+}
+
+func (p Pwned) checkOnline(ctx context.Context, password string) (Result, error) {
+
+	// Encode password
+	encodedPassword := p.encode(password)
+	passwordPrefix := encodedPassword[:5]
+	passwordSuffix := encodedPassword[5:]
+
+	// Without caches nor coalescing nobody else needs the range, so it is only
+	// read until the password suffix is found
+	if p.cache == nil && p.diskCache == nil && p.flights == nil {
+		occurrences, err := p.lookupRange(ctx, passwordPrefix, passwordSuffix)
 		if err != nil {
-			return false, fmt.Errorf("%s", err)
+			return Result{}, err
+		}
+		return p.evaluate(occurrences), nil
+	}
+
+	// Retrieve the suffixes known for the password prefix
+	suffixes, err := p.rangeFor(ctx, passwordPrefix)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Find a password suffix match in request results
+	return p.evaluate(suffixes[passwordSuffix]), nil
+}
+
+// evaluate applies the configured occurrence thresholds to the number of
+// times a password has been seen in breaches.
+func (p Pwned) evaluate(occurrences int) Result {
+
+	minOccurrences := p.MinOccurrences
+	if minOccurrences < 1 {
+		minOccurrences = 1
+	}
+
+	return Result{
+		Secure:      occurrences < minOccurrences,
+		Occurrences: occurrences,
+		Warning:     p.WarnOccurrences > 0 && occurrences >= p.WarnOccurrences && occurrences < minOccurrences,
+	}
+}
+
+// rangeFor returns the suffixes for a hash prefix, from the cache if enabled or
+// by requesting them to the pwned service otherwise. When coalescing is enabled
+// concurrent requests for the same prefix share a single upstream call, which
+// is only cancelled once all of them have been.
+func (p Pwned) rangeFor(ctx context.Context, passwordPrefix string) (rangeSuffixes, error) {
+
+	if p.cache != nil {
+		if suffixes, ok := p.cache.get(passwordPrefix); ok {
+			return suffixes, nil
+		}
+	}
+
+	if p.flights != nil {
+		return p.flights.do(ctx, passwordPrefix, func(ctx context.Context) (rangeSuffixes, error) {
+			return p.fetchRange(ctx, passwordPrefix)
+		})
+	}
+
+	return p.fetchRange(ctx, passwordPrefix)
+}
+
+func (p Pwned) fetchRange(ctx context.Context, passwordPrefix string) (rangeSuffixes, error) {
+
+	// Use the response stored on disk if still fresh, or revalidate it
+	diskKey := p.diskCacheKey(passwordPrefix)
+	var stored *diskCacheEntry
+	var storedSuffixes rangeSuffixes
+	if p.diskCache != nil {
+		if stored, _ = p.diskCache.get(diskKey); stored != nil {
+			var err error
+			if storedSuffixes, err = readRange(strings.NewReader(stored.body)); err != nil {
+				stored = nil
+			} else if p.diskCache.fresh(stored) {
+				return p.cacheRange(passwordPrefix, storedSuffixes), nil
+			}
+		}
+	}
+
+	// Keep a copy of the body to store it on disk
+	var suffixes rangeSuffixes
+	var body strings.Builder
+	response, err := p.requestRange(ctx, passwordPrefix, stored, func(reader io.Reader) error {
+		body.Reset()
+		if p.diskCache != nil {
+			reader = io.TeeReader(reader, &body)
+		}
+
+		var err error
+		suffixes, err = readRange(reader)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A failure to store the response only costs a request on the next miss
+	if response.notModified {
+		suffixes = storedSuffixes
+		p.diskCache.touch(diskKey)
+	} else if p.diskCache != nil {
+		p.diskCache.put(diskKey, &diskCacheEntry{body: body.String(), etag: response.etag, lastModified: response.lastModified})
+	}
+
+	return p.cacheRange(passwordPrefix, suffixes), nil
+}
+
+// lookupRange requests the range for a hash prefix and reads it until the
+// suffix is found.
+func (p Pwned) lookupRange(ctx context.Context, passwordPrefix, passwordSuffix string) (int, error) {
+
+	var occurrences int
+	_, err := p.requestRange(ctx, passwordPrefix, nil, func(reader io.Reader) error {
+		var err error
+		occurrences, err = findSuffix(reader, passwordSuffix)
+		return err
+	})
+
+	return occurrences, err
+}
+
+// requestRange requests the range for a hash prefix to the pwned service,
+// which read consumes as it is received. When stored is set the request is
+// conditional, and read isn't called if the range hasn't changed.
+func (p Pwned) requestRange(ctx context.Context, passwordPrefix string, stored *diskCacheEntry, read func(body io.Reader) error) (pwnedResponse, error) {
+
+	// Create request to pwned service
+	pwnedRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, p.rangeURL(passwordPrefix), nil)
+	if err != nil {
+		return pwnedResponse{}, fmt.Errorf("could not create http request to pwned service")
+	}
+	if p.Padding {
+		pwnedRequest.Header.Set("Add-Padding", "true")
+	}
+	if stored != nil {
+		stored.conditional(pwnedRequest)
+	}
+
+	// Execute request to pwned service, unless the circuit breaker is open
+	var generation uint64
+	if p.breaker != nil {
+		if generation, err = p.breaker.allow(); err != nil {
+			return pwnedResponse{}, err
 		}
+	}
 
-		// Find a password suffix match in request results
-		found := isPasswordInDictionary(passwordSuffix, responseBody)
-		if found {
-			return false, nil
+	// Every attempt waits for the rate limit, if any
+	send := requestFunc(requestPwnedService)
+	if p.limiter != nil {
+		send = p.limiter.limit(send)
+	}
+
+	var response pwnedResponse
+	if p.retrier != nil {
+		response, err = p.retrier.do(send, p.httpClient(), pwnedRequest, p.Timeout*time.Second, read)
+	} else {
+		response, err = send(p.httpClient(), pwnedRequest, p.Timeout*time.Second, read)
+	}
+	if p.breaker != nil {
+		// Requests cancelled by the caller, or throttled before being sent,
+		// don't tell about the pwned service
+		if ctx.Err() == context.Canceled || errors.Is(err, ErrThrottled) {
+			p.breaker.release(generation)
+		} else {
+			p.breaker.record(generation, err)
 		}
 	}
 
-	return true, nil
+	return response, err
+}
+
+func (p Pwned) cacheRange(passwordPrefix string, suffixes rangeSuffixes) rangeSuffixes {
+	if p.cache != nil {
+		p.cache.add(passwordPrefix, suffixes)
+	}
+	return suffixes
+}
+
+// diskCacheKey names the file holding a range, as NTLM and SHA-1 ranges share
+// prefixes.
+func (p Pwned) diskCacheKey(passwordPrefix string) string {
+	if p.Hash == HashNTLM {
+		return passwordPrefix + "." + HashNTLM
+	}
+	return passwordPrefix
+}
+
+func (p Pwned) checkOffline(dataset *Dataset, password string) (Result, error) {
+
+	if dataset == nil {
+		return Result{}, fmt.Errorf("offline dataset is not loaded")
+	}
+
+	occurrences, _, err := dataset.Lookup(p.encode(password))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return p.evaluate(occurrences), nil
+}
+
+// encode hashes the password with the configured hash function
+func (p Pwned) encode(password string) string {
+	if p.Hash == HashNTLM {
+		return encodeToNTLM(password)
+	}
+	return encodeToSHA1(password)
+}
+
+// rangeURL returns the url listing the hash suffixes for a prefix
+func (p Pwned) rangeURL(passwordPrefix string) string {
+	if p.Hash == HashNTLM {
+		return p.URL + passwordPrefix + "?mode=ntlm"
+	}
+	return p.URL + passwordPrefix
+}
+
+// checkBloom answers from the bloom filter, which can't tell how many times a
+// password has been seen. Hits are optionally confirmed against the pwned
+// service to rule out false positives and find out the occurrences.
+func (p Pwned) checkBloom(ctx context.Context, filter *BloomFilter, confirm bool, password string) (Result, error) {
+
+	if filter == nil {
+		return Result{}, fmt.Errorf("bloom filter is not loaded")
+	}
+
+	hash, err := hex.DecodeString(p.encode(password))
+	if err != nil {
+		return Result{}, err
+	}
+
+	if !filter.Contains(hash) {
+		return Result{Secure: true}, nil
+	}
+
+	if confirm {
+		return p.checkOnline(ctx, password)
+	}
+
+	return Result{Secure: false}, nil
 }
 
 func encodeToSHA1(str string) string {
@@ -77,7 +576,26 @@ func encodeToSHA1(str string) string {
 	return strings.ToUpper(fmt.Sprintf("%x", string(bytes)))
 }
 
-func requestPwnedService(client *http.Client, request *http.Request, timeout time.Duration) (string, error) {
+// encodeToNTLM hashes the password as Windows does, MD4 over its UTF-16
+// little endian representation.
+func encodeToNTLM(str string) string {
+	encoded := utf16.Encode([]rune(str))
+	bytes := make([]byte, 2*len(encoded))
+	for i, char := range encoded {
+		binary.LittleEndian.PutUint16(bytes[2*i:], char)
+	}
+
+	h := md4.New()
+	h.Write(bytes)
+
+	return strings.ToUpper(fmt.Sprintf("%x", h.Sum(nil)))
+}
+
+// requestPwnedService executes request, which is expected to return a range,
+// and calls read with the body as it is received. Conditional requests may get
+// a response without body telling the range hasn't changed, read isn't called
+// then.
+func requestPwnedService(client *http.Client, request *http.Request, timeout time.Duration, read func(body io.Reader) error) (pwnedResponse, error) {
 
 	timeoutRequest, cancelFunc := context.WithTimeout(request.Context(), timeout)
 	defer cancelFunc()
@@ -85,33 +603,29 @@ func requestPwnedService(client *http.Client, request *http.Request, timeout tim
 
 	response, err := client.Do(request)
 	if err != nil {
-		return "", fmt.Errorf("could not reach pwned service")
+		return pwnedResponse{}, newUpstreamError(ErrTransport, err)
 	}
+	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("could not obtain a successful response from pnwed service")
+	conditional := request.Header.Get("If-None-Match") != "" || request.Header.Get("If-Modified-Since") != ""
+	if response.StatusCode == http.StatusNotModified && conditional {
+		return pwnedResponse{notModified: true}, nil
 	}
 
-	defer response.Body.Close()
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return "", fmt.Errorf("error when reading pwned response")
+	if response.StatusCode != http.StatusOK {
+		return pwnedResponse{}, &ErrUpstreamStatus{Code: response.StatusCode, RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"))}
 	}
 
-	return string(body), nil
-}
-
-func isPasswordInDictionary(passwordSuffix string, responseBody string) bool {
-
-	lines := strings.Split(string(responseBody), "\n")
-	for _, line := range lines {
-
-		if len(line) > 0 && strings.Contains(line, ":") {
-			if result := strings.Compare(passwordSuffix, line[:strings.LastIndex(line, ":")]); result == 0 {
-				return true
-			}
-		}
+	body := newCappedReader(response.Body, maxRangeSize)
+	if err := read(body); err != nil {
+		return pwnedResponse{}, newUpstreamError(ErrRead, err)
 	}
 
-	return false
+	// What read didn't need is left unread: closing the body then closes the
+	// connection instead of reusing it, which is cheaper than reading up to
+	// maxRangeSize for an answer that doesn't depend on it
+	return pwnedResponse{
+		etag:         response.Header.Get("ETag"),
+		lastModified: response.Header.Get("Last-Modified"),
+	}, nil
 }
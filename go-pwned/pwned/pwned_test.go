@@ -1,14 +1,35 @@
 package pwned
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/password/normalize"
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
 )
 
+// discardBody reads nothing of a range response.
+func discardBody(body io.Reader) error {
+	return nil
+}
+
+func readBody(body *string) func(io.Reader) error {
+	return func(reader io.Reader) error {
+		read, err := io.ReadAll(reader)
+		*body = string(read)
+		return err
+	}
+}
+
 func TestExecutePwnedRequestShouldTimeoutIfServerTooSlow(t *testing.T) {
 
 	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
@@ -27,7 +48,7 @@ func TestExecutePwnedRequestShouldTimeoutIfServerTooSlow(t *testing.T) {
 	// u, _ := url.Parse(server.URL)
 	// request.URL = u
 	//Request to pwned service timesout after waitinf for 1 second
-	_, err := requestPwnedService(client, request, 1*time.Second)
+	_, err := requestPwnedService(client, request, 1*time.Second, discardBody)
 	if err == nil {
 		t.Errorf("Was expecting an error for due to timeout\n")
 	}
@@ -54,11 +75,12 @@ func TestRequestPwnedServiceShouldReturnResponseBody(t *testing.T) {
 	request.RequestURI = ""
 	u, _ := url.Parse(server.URL)
 	request.URL = u
-	response, err := requestPwnedService(client, request, 1*time.Second)
+	var body string
+	_, err := requestPwnedService(client, request, 1*time.Second, readBody(&body))
 	if err != nil {
 		t.Errorf("Got unexpected error: %s\n", err)
 	}
-	if result := strings.Compare(response, responseBody); result != 0 {
+	if result := strings.Compare(body, responseBody); result != 0 {
 		t.Errorf("Response returned unexpected body")
 	}
 
@@ -66,17 +88,16 @@ func TestRequestPwnedServiceShouldReturnResponseBody(t *testing.T) {
 
 func TestRequestPwnedServiceRespondsWithErrorIfStatusNotOk(t *testing.T) {
 
-	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
-		rw.WriteHeader(http.StatusInternalServerError)
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(serverHandler))
+	fake := pwnedtest.NewServer()
+	fake.FailNext(1, http.StatusInternalServerError)
+	server := httptest.NewServer(fake)
 	defer server.Close()
 
 	client := server.Client()
-	request := httptest.NewRequest(http.MethodGet, server.URL, nil)
+	request := httptest.NewRequest(http.MethodGet, server.URL+"/range/EBFC7", nil)
+	request.RequestURI = ""
 
-	_, err := requestPwnedService(client, request, 1*time.Second)
+	_, err := requestPwnedService(client, request, 1*time.Second, discardBody)
 	if err == nil {
 		t.Error("Was expecting an error from pwned service", err)
 	}
@@ -116,7 +137,11 @@ func TestIsPasswordInDictionary(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		found := isPasswordInDictionary(test.passwordSuffix, test.responseBody)
+		suffixes, err := readRange(strings.NewReader(test.responseBody))
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		_, found := suffixes[test.passwordSuffix]
 		if found != test.expectedResult {
 			t.Errorf("Scenario '%s'. Got %t. Expected %t.", test.scenario, found, test.expectedResult)
 		}
@@ -125,16 +150,14 @@ func TestIsPasswordInDictionary(t *testing.T) {
 
 func TestIsSecurePasswordRetursOkIfNotEnabled(t *testing.T) {
 
-	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
-		rw.WriteHeader(http.StatusInternalServerError)
-	}
-
 	// Server should return an errof if invoked.
-	server := httptest.NewServer(http.HandlerFunc(serverHandler))
+	fake := pwnedtest.NewServer()
+	fake.SetFailureRate(1, http.StatusInternalServerError)
+	server := httptest.NewServer(fake)
 	defer server.Close()
-	pwned := Pwned{false, 2 * time.Second, server.URL + "/"}
+	pwned := Pwned{Enabled: false, Timeout: 2 * time.Second, URL: server.URL + "/range/"}
 
-	isSecure, err := pwned.IsSecurePassword("AnyPassw0rd!")
+	isSecure, err := pwned.IsSecurePassword(context.Background(), "AnyPassw0rd!")
 	if isSecure != true {
 		t.Errorf("Expected isSecure: '%t', got: '%t'\n", true, isSecure)
 	}
@@ -143,47 +166,41 @@ func TestIsSecurePasswordRetursOkIfNotEnabled(t *testing.T) {
 		t.Errorf("Wasn't expecting an error, got: '%s'\n", err)
 	}
 
+	if requests := fake.Requests(); requests != 0 {
+		t.Errorf("Expected %d requests to pwned service, Got: %d\n", 0, requests)
+	}
+
 }
 
 func TestIsSecurePasswordForScenarios(t *testing.T) {
 
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
 	tests := []struct {
-		scenario          string
-		password          string
-		pwnedResponseBody string
-		expectedSecure    bool
+		scenario       string
+		password       string
+		expectedSecure bool
 	}{
 		{
-			scenario: "Should return false if password is not secure",
-			password: "Passw0rd",
-			pwnedResponseBody: "52417CB061186A45A9D36B636D126D79A83:5\n" +
-				"910077770C8340F63CD2DCA2AC1F120444F:4\n" +
-				"53772044DE4A4A934FB68DBC55B504804EA:8\n",
+			scenario:       "Should return false if password is not secure",
+			password:       "Passw0rd",
 			expectedSecure: false,
 		},
 		{
-			scenario: "Should return true if password is secure",
-			password: "Passw0rdSec*",
-			pwnedResponseBody: "592EFB557DA4C2F1A267CDD11C6E9A77851:1\n" +
-				"595A7EE9F2033FE3004AC0B3EDEB01B56AD:3\n" +
-				"596DA3F87BDF9A73DB84BA7108C46160613:1\n",
+			scenario:       "Should return true if password is secure",
+			password:       "Passw0rdSec*",
 			expectedSecure: true,
 		},
 	}
 
 	for _, test := range tests {
 
-		serverHandler := func(rw http.ResponseWriter, r *http.Request) {
-			rw.WriteHeader(http.StatusOK)
-			rw.Write([]byte(test.pwnedResponseBody))
-		}
-
-		server := httptest.NewServer(http.HandlerFunc(serverHandler))
-		defer server.Close()
+		pwned := Pwned{Enabled: true, Timeout: 2 * time.Second, URL: server.URL + "/range/"}
 
-		pwned := Pwned{true, 2 * time.Second, server.URL + "/"}
-
-		isSecure, err := pwned.IsSecurePassword(test.password)
+		isSecure, err := pwned.IsSecurePassword(context.Background(), test.password)
 		if err != nil {
 			t.Errorf("Wasn't expecting error for scenario '%s'. Got %v\n", test.scenario, err)
 		}
@@ -196,16 +213,14 @@ func TestIsSecurePasswordForScenarios(t *testing.T) {
 
 func TestIsSecurePasswordShouldReturnErrorIfPwnedServiceReturnsError(t *testing.T) {
 
-	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
-		rw.WriteHeader(http.StatusInternalServerError)
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(serverHandler))
+	fake := pwnedtest.NewServer()
+	fake.SetFailureRate(1, http.StatusInternalServerError)
+	server := httptest.NewServer(fake)
 	defer server.Close()
 
-	pwned := Pwned{true, 2 * time.Second, server.URL}
+	pwned := Pwned{Enabled: true, Timeout: 2 * time.Second, URL: server.URL + "/range/"}
 
-	_, err := pwned.IsSecurePassword("AnyPassw0rd")
+	_, err := pwned.IsSecurePassword(context.Background(), "AnyPassw0rd")
 	if err == nil {
 		t.Error("Was expecting error")
 	}
@@ -239,3 +254,476 @@ func TestEncodeSHA1(t *testing.T) {
 	}
 
 }
+
+func TestParseRangeShouldKeepOccurrences(t *testing.T) {
+
+	suffixes, err := readRange(strings.NewReader("0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n" +
+		"078957725007D81F20E2354088A04162EC9:1\r\n" +
+		"0790D55E682FDBFDE7DAF7FCAA14BAE6C71:1717\r\n"))
+	if err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	expected := map[string]int{
+		"0784E50CD59416AFA6E9E22DEBDA9603901": 5,
+		"078957725007D81F20E2354088A04162EC9": 1,
+		"0790D55E682FDBFDE7DAF7FCAA14BAE6C71": 1717,
+	}
+
+	if len(suffixes) != len(expected) {
+		t.Errorf("Expected %d suffixes, Got: %d\n", len(expected), len(suffixes))
+	}
+	for suffix, count := range expected {
+		if suffixes[suffix] != count {
+			t.Errorf("Suffix '%s'. Expected %d occurrences, Got: %d\n", suffix, count, suffixes[suffix])
+		}
+	}
+}
+
+func TestCheckShouldApplyOccurrenceThresholds(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 40)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		scenario        string
+		password        string
+		minOccurrences  int
+		warnOccurrences int
+		expected        Result
+	}{
+		{
+			scenario: "Should reject a password seen once by default",
+			password: "Passw0rd",
+			expected: Result{Secure: false, Occurrences: 40},
+		},
+		{
+			scenario:       "Should reject a password seen at least minOccurrences times",
+			password:       "Passw0rd",
+			minOccurrences: 40,
+			expected:       Result{Secure: false, Occurrences: 40},
+		},
+		{
+			scenario:       "Should accept a password seen fewer than minOccurrences times",
+			password:       "Passw0rd",
+			minOccurrences: 41,
+			expected:       Result{Secure: true, Occurrences: 40},
+		},
+		{
+			scenario:        "Should warn about a password accepted within the warning band",
+			password:        "Passw0rd",
+			minOccurrences:  100,
+			warnOccurrences: 10,
+			expected:        Result{Secure: true, Occurrences: 40, Warning: true},
+		},
+		{
+			scenario:        "Should not warn about a password below the warning band",
+			password:        "Passw0rd",
+			minOccurrences:  100,
+			warnOccurrences: 50,
+			expected:        Result{Secure: true, Occurrences: 40},
+		},
+		{
+			scenario:        "Should not warn about a password not found",
+			password:        "Passw0rdSec*",
+			minOccurrences:  100,
+			warnOccurrences: 1,
+			expected:        Result{Secure: true, Occurrences: 0},
+		},
+	}
+
+	for _, test := range tests {
+
+		pwned := Pwned{
+			Enabled:         true,
+			Timeout:         2,
+			URL:             server.URL + "/range/",
+			MinOccurrences:  test.minOccurrences,
+			WarnOccurrences: test.warnOccurrences,
+		}
+
+		result, err := pwned.Check(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result != test.expected {
+			t.Errorf("Scenario '%s'. Expected: %+v, Got: %+v\n", test.scenario, test.expected, result)
+		}
+	}
+}
+
+func TestCheckShouldRequestAndIgnorePadding(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 5)
+	var receivedHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		receivedHeader = r.Header.Get("Add-Padding")
+		fake.ServeHTTP(rw, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		scenario       string
+		padding        bool
+		expectedHeader string
+	}{
+		{
+			scenario:       "Should request padding when enabled",
+			padding:        true,
+			expectedHeader: "true",
+		},
+		{
+			scenario:       "Should not request padding when disabled",
+			padding:        false,
+			expectedHeader: "",
+		},
+	}
+
+	for _, test := range tests {
+
+		receivedHeader = ""
+		cache := newRangeCache(time.Minute, 0, noopMetrics{})
+		pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Padding: test.padding, cache: cache}
+		result, err := pwned.Check(context.Background(), "Passw0rd")
+
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if receivedHeader != test.expectedHeader {
+			t.Errorf("Scenario '%s'. Expected Add-Padding header '%s', Got: '%s'\n", test.scenario, test.expectedHeader, receivedHeader)
+		}
+		if result.Secure || result.Occurrences != 5 {
+			t.Errorf("Scenario '%s'. Expected an insecure password seen 5 times, Got: %+v\n", test.scenario, result)
+		}
+
+		// "Passw0rd" has the SHA-1 prefix EBFC7, and padding entries are never seen
+		suffixes, _ := cache.get("EBFC7")
+		if entries := len(suffixes); entries != 1 {
+			t.Errorf("Scenario '%s'. Expected %d entries once padding is ignored, Got: %d\n", test.scenario, 1, entries)
+		}
+	}
+}
+
+func TestEncodeNTLM(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		inputStr string
+		expected string
+	}{
+		{
+			scenario: "Should encode an empty password to ntlm",
+			inputStr: "",
+			expected: "31D6CFE0D16AE931B73C59D7E0C089C0",
+		},
+		{
+			scenario: "Should encode password to ntlm",
+			inputStr: "password",
+			expected: "8846F7EAEE8FB117AD06BDD830B7586C",
+		},
+	}
+
+	for _, test := range tests {
+		got := encodeToNTLM(test.inputStr)
+		if result := strings.Compare(test.expected, got); result != 0 {
+			t.Errorf("Scenario: '%s'. Expected: %s. Got %s.\n", test.scenario, test.expected, got)
+		}
+	}
+
+}
+
+func TestCheckShouldLookUpNTLMHashes(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("password", 9)
+	var requestedPath, requestedMode string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		requestedMode = r.URL.Query().Get("mode")
+		fake.ServeHTTP(rw, r)
+	}))
+	defer server.Close()
+
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Hash: HashNTLM}
+	result, err := pwned.Check(context.Background(), "password")
+	if err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	if requestedPath != "/range/8846F" {
+		t.Errorf("Expected request to '%s', Got: '%s'\n", "/range/8846F", requestedPath)
+	}
+	if requestedMode != HashNTLM {
+		t.Errorf("Expected mode query '%s', Got: '%s'\n", HashNTLM, requestedMode)
+	}
+	if result.Secure || result.Occurrences != 9 {
+		t.Errorf("Expected an insecure password seen 9 times, Got: %+v\n", result)
+	}
+}
+
+func TestCheckShouldStopWhenContextIsCancelled(t *testing.T) {
+
+	cancelled := make(chan struct{})
+	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(serverHandler))
+	defer server.Close()
+
+	breaker := newCircuitBreaker(CircuitBreaker{ConsecutiveFailures: 1, OpenTimeout: 60 * time.Second}, noopMetrics{})
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/", breaker: breaker}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := pwned.Check(ctx, "Passw0rd")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error, Got: '%v'\n", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the request to pwned service to be cancelled")
+	}
+
+	if state := breaker.currentState(); state != BreakerClosed {
+		t.Errorf("Expected cancelled requests not to open the circuit, Got: %s\n", state)
+	}
+}
+
+func TestCheckShouldFindPasswordsInFakeRangeAPI(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 12)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		scenario            string
+		pwned               Pwned
+		password            string
+		expectedOccurrences int
+	}{
+		{
+			scenario:            "Should find a password by its SHA-1 hash",
+			pwned:               Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/"},
+			password:            "Passw0rd",
+			expectedOccurrences: 12,
+		},
+		{
+			scenario:            "Should find a password by its NTLM hash",
+			pwned:               Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Hash: HashNTLM},
+			password:            "Passw0rd",
+			expectedOccurrences: 12,
+		},
+		{
+			scenario:            "Should find a password in a padded range",
+			pwned:               Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Padding: true, cache: newRangeCache(time.Minute, 0, noopMetrics{})},
+			password:            "Passw0rd",
+			expectedOccurrences: 12,
+		},
+		{
+			scenario:            "Should not find a password never seen",
+			pwned:               Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Padding: true},
+			password:            "ThisIsPassword1",
+			expectedOccurrences: 0,
+		},
+	}
+
+	for _, test := range tests {
+
+		result, err := test.pwned.Check(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result.Occurrences != test.expectedOccurrences {
+			t.Errorf("Scenario '%s'. Expected %d occurrences, Got: %d\n", test.scenario, test.expectedOccurrences, result.Occurrences)
+		}
+	}
+}
+
+func TestCheckShouldLookUpNormalizedVariants(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Password", 9)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		scenario            string
+		normalize           normalize.Normalization
+		password            string
+		expectedSecure      bool
+		expectedOccurrences int
+		expectedRequests    int
+	}{
+		{
+			scenario:         "Should only look up the password without normalization",
+			password:         "P@55w0rd",
+			expectedSecure:   true,
+			expectedRequests: 1,
+		},
+		{
+			scenario:            "Should find passwords spelled in leet",
+			normalize:           normalize.Normalization{Leet: true},
+			password:            "P@55w0rd",
+			expectedOccurrences: 9,
+			expectedRequests:    2,
+		},
+		{
+			scenario:            "Should find passwords written with look-alikes",
+			normalize:           normalize.Normalization{Confusables: true},
+			password:            "Раssword",
+			expectedOccurrences: 9,
+			expectedRequests:    2,
+		},
+		{
+			scenario:         "Should accept passwords when no variant has been seen",
+			normalize:        normalize.Normalization{NFKC: true, Confusables: true, Leet: true},
+			password:         "Tr0ub4dour&3",
+			expectedSecure:   true,
+			expectedRequests: 2,
+		},
+	}
+
+	for _, test := range tests {
+
+		requests := fake.Requests()
+		pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", Normalize: test.normalize}
+		result, err := pwned.Check(context.Background(), test.password)
+		if err != nil {
+			t.Errorf("Scenario '%s'. Wasn't expecting error, Got: '%s'\n", test.scenario, err)
+		}
+		if result.Secure != test.expectedSecure || result.Occurrences != test.expectedOccurrences {
+			t.Errorf("Scenario '%s'. Expected secure %t with %d occurrences, Got: %t with %d\n", test.scenario, test.expectedSecure, test.expectedOccurrences, result.Secure, result.Occurrences)
+		}
+		if made := fake.Requests() - requests; made != test.expectedRequests {
+			t.Errorf("Scenario '%s'. Expected %d requests, Got: %d\n", test.scenario, test.expectedRequests, made)
+		}
+	}
+}
+
+func TestNewPwnedConfigShouldRejectUnknownValues(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		config        string
+		expectedPanic string
+	}{
+		{
+			scenario:      "Should reject an unknown mode",
+			config:        "pwned:\n  mode: ofline\n",
+			expectedPanic: "Unknown mode 'ofline' for Pwned",
+		},
+		{
+			scenario:      "Should reject an unknown hash",
+			config:        "pwned:\n  hash: NTLM\n",
+			expectedPanic: "Unknown hash 'NTLM' for Pwned",
+		},
+		{
+			scenario:      "Should reject an unknown failure policy",
+			config:        "pwned:\n  failurePolicy: opened\n",
+			expectedPanic: "Unknown failure policy 'opened' for Pwned",
+		},
+		{
+			scenario: "Should accept known values",
+			config:   "pwned:\n  mode: online\n  hash: ntlm\n  failurePolicy: open\n",
+		},
+		{
+			scenario: "Should accept missing values",
+			config:   "pwned:\n  enabled: false\n",
+		},
+	}
+
+	for _, test := range tests {
+
+		path := filepath.Join(t.TempDir(), "pwned-config.yml")
+		if err := os.WriteFile(path, []byte(test.config), 0644); err != nil {
+			t.Fatalf("Could not write configuration: %s", err)
+		}
+
+		panicked := func() (recovered interface{}) {
+			defer func() { recovered = recover() }()
+			NewPwnedConfig(path, nil)
+			return nil
+		}()
+
+		if test.expectedPanic == "" && panicked != nil {
+			t.Errorf("Scenario '%s'. Expected no panic, Got: %v\n", test.scenario, panicked)
+		}
+		if test.expectedPanic != "" && panicked != test.expectedPanic {
+			t.Errorf("Scenario '%s'. Expected panic '%s', Got: %v\n", test.scenario, test.expectedPanic, panicked)
+		}
+	}
+}
+
+func TestNewPwnedConfigShouldConvertUnits(t *testing.T) {
+
+	config := `pwned:
+  cache:
+    ttlSeconds: 300
+  circuitBreaker:
+    windowSeconds: 30
+    openSeconds: 60
+  retry:
+    initialBackoffMillis: 100
+    maxBackoffMillis: 2000
+  http:
+    idleConnTimeoutSeconds: 90
+  diskCache:
+    maxSizeMB: 100
+    ttlSeconds: 3600
+  rateLimit:
+    queueTimeoutMillis: 250
+  warmUp:
+    refreshSeconds: 86400
+    readyTimeoutSeconds: 600
+  providers:
+    - type: remote
+      timeoutMillis: 500
+`
+	path := filepath.Join(t.TempDir(), "pwned-config.yml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("Could not write configuration: %s", err)
+	}
+
+	pwned := NewPwnedConfig(path, nil)
+
+	tests := []struct {
+		field    string
+		value    time.Duration
+		expected time.Duration
+	}{
+		{"cache.ttlSeconds", pwned.Cache.TTL, 5 * time.Minute},
+		{"circuitBreaker.windowSeconds", pwned.CircuitBreaker.Window, 30 * time.Second},
+		{"circuitBreaker.openSeconds", pwned.CircuitBreaker.OpenTimeout, 60 * time.Second},
+		{"retry.initialBackoffMillis", pwned.Retry.InitialBackoff, 100 * time.Millisecond},
+		{"retry.maxBackoffMillis", pwned.Retry.MaxBackoff, 2000 * time.Millisecond},
+		{"http.idleConnTimeoutSeconds", pwned.HTTP.IdleConnTimeout, 90 * time.Second},
+		{"diskCache.ttlSeconds", pwned.DiskCache.TTL, time.Hour},
+		{"rateLimit.queueTimeoutMillis", pwned.RateLimit.QueueTimeout, 250 * time.Millisecond},
+		{"warmUp.refreshSeconds", pwned.WarmUp.Refresh, 24 * time.Hour},
+		{"warmUp.readyTimeoutSeconds", pwned.WarmUp.ReadyTimeout, 10 * time.Minute},
+		{"providers[0].timeoutMillis", pwned.Providers[0].Timeout, 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if test.value != test.expected {
+			t.Errorf("Expected %s to be %s, Got: %s\n", test.field, test.expected, test.value)
+		}
+	}
+
+	if expected := int64(100 * 1024 * 1024); pwned.DiskCache.MaxSize != expected {
+		t.Errorf("Expected diskCache.maxSizeMB to be %d bytes, Got: %d\n", expected, pwned.DiskCache.MaxSize)
+	}
+}
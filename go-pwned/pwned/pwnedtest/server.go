@@ -0,0 +1,294 @@
+// Package pwnedtest provides a fake of the range API of Have I Been Pwned, to
+// be used by tests and local environments instead of the real service.
+//
+//	fake := pwnedtest.NewServer()
+//	fake.Add("Passw0rd", 12)
+//	server := httptest.NewServer(fake)
+//	defer server.Close()
+//	// Use server.URL + "/range/" as the pwned url
+package pwnedtest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// PaddingSize is the number of entries padded responses are filled up to.
+const PaddingSize = 800
+
+// Lengths of the hexadecimal hashes once their 5 character prefix is removed.
+const (
+	sha1SuffixSize = 35
+	ntlmSuffixSize = 27
+)
+
+type (
+	// Server serves the range API from an in-memory list of passwords, in both
+	// SHA-1 and NTLM modes. It is safe for concurrent use, and can be changed
+	// while serving.
+	Server struct {
+		mutex    sync.Mutex
+		sha1     map[string]map[string]int
+		ntlm     map[string]map[string]int
+		latency  time.Duration
+		failNext int
+		failRate float64
+		failCode int
+		random   *rand.Rand
+		requests int64
+	}
+)
+
+func NewServer() *Server {
+	return &Server{
+		sha1:   make(map[string]map[string]int),
+		ntlm:   make(map[string]map[string]int),
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Add adds a password seen count times in breaches, replacing its count if
+// already added.
+func (s *Server) Add(password string, count int) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	addHash(s.sha1, SHA1(password), count)
+	addHash(s.ntlm, NTLM(password), count)
+}
+
+// Load adds the passwords read from reader, one per line with an optional
+// count as PASSWORD:COUNT. Passwords without count are seen once, and empty
+// lines are skipped.
+func (s *Server) Load(reader io.Reader) error {
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimRight(scanner.Text(), "\r")
+		if entry == "" {
+			continue
+		}
+
+		count := 1
+		if separator := strings.LastIndex(entry, ":"); separator >= 0 {
+			parsed, err := strconv.Atoi(entry[separator+1:])
+			if err == nil {
+				entry, count = entry[:separator], parsed
+			}
+		}
+		if count < 1 {
+			return fmt.Errorf("line %d: invalid count for password", line)
+		}
+		s.Add(entry, count)
+	}
+
+	return scanner.Err()
+}
+
+// LoadFile adds the passwords of the file at path, as Load does.
+func (s *Server) LoadFile(path string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return s.Load(file)
+}
+
+// SetLatency delays every response by latency.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latency = latency
+}
+
+// FailNext answers the next requests requests with status.
+func (s *Server) FailNext(requests int, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failNext = requests
+	s.failCode = status
+}
+
+// SetFailureRate answers a rate, between 0 and 1, of the requests with status.
+func (s *Server) SetFailureRate(rate float64, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failRate = rate
+	s.failCode = status
+}
+
+// Requests returns how many requests have been served.
+func (s *Server) Requests() int {
+	return int(atomic.LoadInt64(&s.requests))
+}
+
+// ServeHTTP serves GET requests to any path ending in /range/{prefix}, looking
+// the prefix up among NTLM hashes when the mode=ntlm query parameter is set.
+// Responses are padded when the Add-Padding header is set, and carry an ETag
+// so conditional requests can be answered with 304.
+func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+
+	atomic.AddInt64(&s.requests, 1)
+
+	if r.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	segments := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] != "range" {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	prefix := strings.ToUpper(segments[len(segments)-1])
+	if len(prefix) != 5 || !isHex(prefix) {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(rw, "The hash prefix was not in a valid format")
+		return
+	}
+
+	latency, failure, body := s.respond(prefix, r.URL.Query().Get("mode") == "ntlm", r.Header.Get("Add-Padding") == "true")
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != 0 {
+		if failure == http.StatusTooManyRequests || failure == http.StatusServiceUnavailable {
+			rw.Header().Set("Retry-After", "1")
+		}
+		rw.WriteHeader(failure)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum([]byte(body)))
+	rw.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.WriteHeader(http.StatusOK)
+	io.WriteString(rw, body)
+}
+
+// respond decides how to answer a request for prefix, returning the failure
+// status to reply with or the body otherwise.
+func (s *Server) respond(prefix string, ntlm bool, padding bool) (time.Duration, int, string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failNext > 0 {
+		s.failNext--
+		return s.latency, s.failCode, ""
+	}
+	if s.failRate > 0 && s.random.Float64() < s.failRate {
+		return s.latency, s.failCode, ""
+	}
+
+	hashes, hashSize := s.sha1, sha1SuffixSize
+	if ntlm {
+		hashes, hashSize = s.ntlm, ntlmSuffixSize
+	}
+
+	suffixes := make(map[string]int, len(hashes[prefix]))
+	for suffix, count := range hashes[prefix] {
+		suffixes[suffix] = count
+	}
+	if padding {
+		pad(prefix, suffixes, hashSize)
+	}
+
+	return s.latency, 0, formatRange(suffixes)
+}
+
+func addHash(hashes map[string]map[string]int, hash string, count int) {
+
+	prefix, suffix := hash[:5], hash[5:]
+	if hashes[prefix] == nil {
+		hashes[prefix] = make(map[string]int)
+	}
+	hashes[prefix][suffix] = count
+}
+
+// pad fills suffixes up to PaddingSize entries with decoys seen 0 times. Decoys
+// are derived from the prefix, so responses for a prefix don't change.
+func pad(prefix string, suffixes map[string]int, hashSize int) {
+
+	for i := 0; len(suffixes) < PaddingSize; i++ {
+		seed := sha1.Sum([]byte(fmt.Sprintf("%s:%d", prefix, i)))
+		decoy := strings.ToUpper(hex.EncodeToString(seed[:]) + hex.EncodeToString(seed[:]))[:hashSize]
+		if _, ok := suffixes[decoy]; !ok {
+			suffixes[decoy] = 0
+		}
+	}
+}
+
+// formatRange lists suffixes sorted, one SUFFIX:COUNT per line as the range API
+// does.
+func formatRange(suffixes map[string]int) string {
+
+	sorted := make([]string, 0, len(suffixes))
+	for suffix := range suffixes {
+		sorted = append(sorted, suffix)
+	}
+	sort.Strings(sorted)
+
+	var body strings.Builder
+	for _, suffix := range sorted {
+		fmt.Fprintf(&body, "%s:%d\r\n", suffix, suffixes[suffix])
+	}
+	return body.String()
+}
+
+// SHA1 returns the upper case hexadecimal SHA-1 hash of password.
+func SHA1(password string) string {
+	hash := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// NTLM returns the upper case hexadecimal NTLM hash of password, the MD4 of
+// its UTF-16LE encoding.
+func NTLM(password string) string {
+
+	hash := md4.New()
+	for _, unit := range utf16.Encode([]rune(password)) {
+		hash.Write([]byte{byte(unit), byte(unit >> 8)})
+	}
+	return strings.ToUpper(hex.EncodeToString(hash.Sum(nil)))
+}
+
+func isHex(value string) bool {
+	for _, char := range value {
+		if !('0' <= char && char <= '9' || 'A' <= char && char <= 'F' || 'a' <= char && char <= 'f') {
+			return false
+		}
+	}
+	return true
+}
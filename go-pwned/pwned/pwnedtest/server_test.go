@@ -0,0 +1,217 @@
+package pwnedtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, server *httptest.Server, path string, header http.Header) (int, http.Header, string) {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	return response.StatusCode, response.Header, string(body)
+}
+
+func TestHashes(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		hash     func(string) string
+		password string
+		expected string
+	}{
+		{
+			scenario: "Should hash to SHA-1",
+			hash:     SHA1,
+			password: "Passw0rd",
+			expected: "EBFC7910077770C8340F63CD2DCA2AC1F120444F",
+		},
+		{
+			scenario: "Should hash to NTLM",
+			hash:     NTLM,
+			password: "password",
+			expected: "8846F7EAEE8FB117AD06BDD830B7586C",
+		},
+		{
+			scenario: "Should hash an empty password to NTLM",
+			hash:     NTLM,
+			password: "",
+			expected: "31D6CFE0D16AE931B73C59D7E0C089C0",
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.hash(test.password); got != test.expected {
+			t.Errorf("Scenario '%s'. Expected: %s, Got: %s\n", test.scenario, test.expected, got)
+		}
+	}
+}
+
+func TestServerShouldServeRanges(t *testing.T) {
+
+	fake := NewServer()
+	fake.Add("Passw0rd", 12)
+	if err := fake.Load(strings.NewReader("password:3\r\n\r\nletmein\r\n")); err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		scenario        string
+		path            string
+		header          http.Header
+		expectedStatus  int
+		expectedLine    string
+		expectedEntries int
+	}{
+		{
+			scenario:        "Should list the suffixes of a SHA-1 prefix",
+			path:            "/range/EBFC7",
+			expectedStatus:  http.StatusOK,
+			expectedLine:    "910077770C8340F63CD2DCA2AC1F120444F:12",
+			expectedEntries: 1,
+		},
+		{
+			scenario:        "Should accept lower case prefixes",
+			path:            "/range/5baa6",
+			expectedStatus:  http.StatusOK,
+			expectedLine:    "1E4C9B93F3F0682250B6CF8331B7EE68FD8:3",
+			expectedEntries: 1,
+		},
+		{
+			scenario:        "Should list the suffixes of a NTLM prefix",
+			path:            "/range/8846F?mode=ntlm",
+			expectedStatus:  http.StatusOK,
+			expectedLine:    "7EAEE8FB117AD06BDD830B7586C:3",
+			expectedEntries: 1,
+		},
+		{
+			scenario:        "Should pad responses if asked to",
+			path:            "/range/EBFC7",
+			header:          http.Header{"Add-Padding": []string{"true"}},
+			expectedStatus:  http.StatusOK,
+			expectedLine:    "910077770C8340F63CD2DCA2AC1F120444F:12",
+			expectedEntries: PaddingSize,
+		},
+		{
+			scenario:        "Should return an empty range for unknown prefixes",
+			path:            "/range/00000",
+			expectedStatus:  http.StatusOK,
+			expectedEntries: 0,
+		},
+		{
+			scenario:       "Should reject invalid prefixes",
+			path:           "/range/EBFCZ",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			scenario:       "Should not serve other paths",
+			path:           "/EBFC7",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+
+		status, _, body := get(t, server, test.path, test.header)
+		if status != test.expectedStatus {
+			t.Errorf("Scenario '%s'. Expected status %d, Got: %d\n", test.scenario, test.expectedStatus, status)
+		}
+		if status != http.StatusOK {
+			continue
+		}
+
+		lines := strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n")
+		if body == "" {
+			lines = nil
+		}
+		if len(lines) != test.expectedEntries {
+			t.Errorf("Scenario '%s'. Expected %d entries, Got: %d\n", test.scenario, test.expectedEntries, len(lines))
+		}
+		if test.expectedLine != "" && !strings.Contains(body, test.expectedLine+"\r\n") {
+			t.Errorf("Scenario '%s'. Expected line '%s' in response, Got: '%s'\n", test.scenario, test.expectedLine, body)
+		}
+	}
+}
+
+func TestServerShouldAnswerConditionalRequests(t *testing.T) {
+
+	fake := NewServer()
+	fake.Add("Passw0rd", 12)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	_, header, _ := get(t, server, "/range/EBFC7", nil)
+	etag := header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	if status, _, _ := get(t, server, "/range/EBFC7", http.Header{"If-None-Match": []string{etag}}); status != http.StatusNotModified {
+		t.Errorf("Expected status %d for an unchanged range, Got: %d\n", http.StatusNotModified, status)
+	}
+
+	fake.Add("Passw0rd", 13)
+	if status, _, _ := get(t, server, "/range/EBFC7", http.Header{"If-None-Match": []string{etag}}); status != http.StatusOK {
+		t.Errorf("Expected status %d for a changed range, Got: %d\n", http.StatusOK, status)
+	}
+}
+
+func TestServerShouldInjectFaults(t *testing.T) {
+
+	fake := NewServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	fake.FailNext(2, http.StatusTooManyRequests)
+	expectedStatuses := []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK}
+	for i, expectedStatus := range expectedStatuses {
+		status, header, _ := get(t, server, "/range/EBFC7", nil)
+		if status != expectedStatus {
+			t.Errorf("Expected request %d status %d, Got: %d\n", i, expectedStatus, status)
+		}
+		if status == http.StatusTooManyRequests && header.Get("Retry-After") == "" {
+			t.Errorf("Expected request %d to carry Retry-After\n", i)
+		}
+	}
+
+	fake.SetFailureRate(1, http.StatusInternalServerError)
+	if status, _, _ := get(t, server, "/range/EBFC7", nil); status != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, Got: %d\n", http.StatusInternalServerError, status)
+	}
+	fake.SetFailureRate(0, 0)
+
+	fake.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	get(t, server, "/range/EBFC7", nil)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the response to be delayed %s, Got: %s\n", 50*time.Millisecond, elapsed)
+	}
+
+	if requests := fake.Requests(); requests != 5 {
+		t.Errorf("Expected %d requests, Got: %d\n", 5, requests)
+	}
+}
+
+func TestLoadShouldRejectInvalidCounts(t *testing.T) {
+
+	if err := NewServer().Load(strings.NewReader("password:0\n")); err == nil {
+		t.Error("Was expecting an error")
+	}
+}
@@ -0,0 +1,167 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// Retry makes up to MaxAttempts requests to the pwned service, waiting an
+	// exponential backoff with jitter between InitialBackoffMillis and
+	// MaxBackoffMillis, or the Retry-After sent by the service, between them.
+	// Retries never exceed the timeoutSeconds budget of the request.
+	Retry struct {
+		MaxAttempts    int           `yaml:"maxAttempts"`
+		InitialBackoff time.Duration `yaml:"initialBackoffMillis"`
+		MaxBackoff     time.Duration `yaml:"maxBackoffMillis"`
+	}
+
+	retrier struct {
+		config  Retry
+		metrics MetricService
+		jitter  func(time.Duration) time.Duration
+		sleep   func(ctx context.Context, wait time.Duration) error
+	}
+)
+
+var (
+	jitterRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMutex  sync.Mutex
+)
+
+func newRetrier(config Retry, metrics MetricService) *retrier {
+
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = config.InitialBackoff
+	}
+
+	return &retrier{config: config, metrics: metrics, jitter: equalJitter, sleep: sleepContext}
+}
+
+// do requests the pwned service with send retrying the failures which may
+// succeed later for as long as attempts, the timeout budget and the request
+// context allow.
+func (r *retrier) do(send requestFunc, client *http.Client, request *http.Request, budget time.Duration, read func(body io.Reader) error) (pwnedResponse, error) {
+
+	deadline := time.Now().Add(budget)
+	for attempt := 1; ; attempt++ {
+
+		response, err := send(client, request, time.Until(deadline), read)
+		if err == nil || attempt >= r.config.MaxAttempts {
+			return response, err
+		}
+
+		reason, wait, retryable := r.retryReason(err, attempt)
+		if !retryable || time.Now().Add(wait).After(deadline) {
+			return response, err
+		}
+
+		r.metrics.Retry(reason)
+		if err := r.sleep(request.Context(), wait); err != nil {
+			return response, newUpstreamError(ErrTransport, err)
+		}
+	}
+}
+
+// retryReason tells whether the request failed with a retryable error, why and
+// how long to wait before retrying it.
+func (r *retrier) retryReason(err error, attempt int) (string, time.Duration, bool) {
+
+	// Retrying throttled requests would only take more of the quota
+	if errors.Is(err, ErrThrottled) {
+		return "", 0, false
+	}
+
+	var statusErr *ErrUpstreamStatus
+	if !errors.As(err, &statusErr) {
+		if errors.Is(err, ErrTimeout) {
+			return "timeout", r.backoff(attempt), true
+		}
+		if errors.Is(err, ErrRead) {
+			return "read", r.backoff(attempt), true
+		}
+		return "transport", r.backoff(attempt), true
+	}
+
+	switch statusErr.Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		wait := r.backoff(attempt)
+		if statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+		return fmt.Sprintf("status_%d", statusErr.Code), wait, true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return fmt.Sprintf("status_%d", statusErr.Code), r.backoff(attempt), true
+	}
+
+	return "", 0, false
+}
+
+func (r *retrier) backoff(attempt int) time.Duration {
+
+	backoff := r.config.InitialBackoff
+	for i := 1; i < attempt && backoff < r.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.config.MaxBackoff {
+		backoff = r.config.MaxBackoff
+	}
+
+	return r.jitter(backoff)
+}
+
+// sleepContext waits for wait, unless ctx is done earlier.
+func sleepContext(ctx context.Context, wait time.Duration) error {
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// equalJitter waits at least half of the backoff, plus a random amount up to
+// the other half.
+func equalJitter(backoff time.Duration) time.Duration {
+
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return time.Duration(half + jitterRandom.Int63n(half+1))
+}
+
+// parseRetryAfter reads a Retry-After header, expressed either in seconds or as
+// an http date.
+func parseRetryAfter(value string) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
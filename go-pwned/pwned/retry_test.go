@@ -0,0 +1,263 @@
+package pwned
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+type testResponse struct {
+	status     int
+	retryAfter int
+	body       string
+}
+
+// newSequenceServer returns a fake range API replying with each of the
+// responses in turn, repeating the last one once they run out.
+func newSequenceServer(responses []testResponse, requests *int32) *httptest.Server {
+
+	serverHandler := func(rw http.ResponseWriter, r *http.Request) {
+		request := int(atomic.AddInt32(requests, 1)) - 1
+		if request >= len(responses) {
+			request = len(responses) - 1
+		}
+
+		response := responses[request]
+		if response.retryAfter > 0 {
+			rw.Header().Set("Retry-After", strconv.Itoa(response.retryAfter))
+		}
+		rw.WriteHeader(response.status)
+		rw.Write([]byte(response.body))
+	}
+
+	return httptest.NewServer(http.HandlerFunc(serverHandler))
+}
+
+func newTestRetrier(config Retry, metrics MetricService, waits *[]time.Duration) *retrier {
+	retrier := newRetrier(config, metrics)
+	retrier.jitter = func(backoff time.Duration) time.Duration { return backoff }
+	retrier.sleep = func(ctx context.Context, wait time.Duration) error {
+		*waits = append(*waits, wait)
+		return nil
+	}
+	return retrier
+}
+
+func TestRetrierShouldRetryFailures(t *testing.T) {
+
+	tests := []struct {
+		scenario         string
+		config           Retry
+		budget           time.Duration
+		responses        []testResponse
+		expectedError    bool
+		expectedRequests int32
+		expectedReasons  []string
+		expectedWaits    []time.Duration
+	}{
+		{
+			scenario: "Should retry until the service succeeds",
+			config:   Retry{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 1000 * time.Millisecond},
+			budget:   10 * time.Second,
+			responses: []testResponse{
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusOK, body: "910077770C8340F63CD2DCA2AC1F120444F:4\n"},
+			},
+			expectedError:    false,
+			expectedRequests: 3,
+			expectedReasons:  []string{"status_503", "status_500"},
+			expectedWaits:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			scenario: "Should respect Retry-After",
+			config:   Retry{MaxAttempts: 2, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 1000 * time.Millisecond},
+			budget:   10 * time.Second,
+			responses: []testResponse{
+				{status: http.StatusTooManyRequests, retryAfter: 2},
+				{status: http.StatusOK},
+			},
+			expectedError:    false,
+			expectedRequests: 2,
+			expectedReasons:  []string{"status_429"},
+			expectedWaits:    []time.Duration{2 * time.Second},
+		},
+		{
+			scenario: "Should cap the backoff",
+			config:   Retry{MaxAttempts: 4, InitialBackoff: 300 * time.Millisecond, MaxBackoff: 500 * time.Millisecond},
+			budget:   10 * time.Second,
+			responses: []testResponse{
+				{status: http.StatusBadGateway},
+			},
+			expectedError:    true,
+			expectedRequests: 4,
+			expectedReasons:  []string{"status_502", "status_502", "status_502"},
+			expectedWaits:    []time.Duration{300 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			scenario: "Should not retry errors which won't succeed later",
+			config:   Retry{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 1000 * time.Millisecond},
+			budget:   10 * time.Second,
+			responses: []testResponse{
+				{status: http.StatusNotFound},
+			},
+			expectedError:    true,
+			expectedRequests: 1,
+		},
+		{
+			scenario: "Should not retry beyond the timeout budget",
+			config:   Retry{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 1000 * time.Millisecond},
+			budget:   2 * time.Second,
+			responses: []testResponse{
+				{status: http.StatusServiceUnavailable, retryAfter: 5},
+				{status: http.StatusOK},
+			},
+			expectedError:    true,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, test := range tests {
+
+		var requests int32
+		server := newSequenceServer(test.responses, &requests)
+
+		metrics := &testMetrics{}
+		var waits []time.Duration
+		retrier := newTestRetrier(test.config, metrics, &waits)
+
+		request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		_, err := retrier.do(requestPwnedService, server.Client(), request, test.budget, discardBody)
+		server.Close()
+
+		if (err != nil) != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error: %t, Got: %v\n", test.scenario, test.expectedError, err)
+		}
+		if requests != test.expectedRequests {
+			t.Errorf("Scenario '%s'. Expected %d requests, Got: %d\n", test.scenario, test.expectedRequests, requests)
+		}
+		if len(metrics.retries) != len(test.expectedReasons) {
+			t.Errorf("Scenario '%s'. Expected retries %v, Got: %v\n", test.scenario, test.expectedReasons, metrics.retries)
+		} else {
+			for i := range test.expectedReasons {
+				if metrics.retries[i] != test.expectedReasons[i] {
+					t.Errorf("Scenario '%s'. Expected retries %v, Got: %v\n", test.scenario, test.expectedReasons, metrics.retries)
+					break
+				}
+			}
+		}
+		if len(waits) != len(test.expectedWaits) {
+			t.Errorf("Scenario '%s'. Expected waits %v, Got: %v\n", test.scenario, test.expectedWaits, waits)
+		} else {
+			for i := range test.expectedWaits {
+				if waits[i] != test.expectedWaits[i] {
+					t.Errorf("Scenario '%s'. Expected waits %v, Got: %v\n", test.scenario, test.expectedWaits, waits)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestRetrierShouldRetryTransportErrors(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	metrics := &testMetrics{}
+	var waits []time.Duration
+	retrier := newTestRetrier(Retry{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}, metrics, &waits)
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	if _, err := retrier.do(requestPwnedService, &http.Client{}, request, 2*time.Second, discardBody); err == nil {
+		t.Error("Was expecting an error")
+	}
+
+	if len(metrics.retries) != 2 || metrics.retries[0] != "transport" {
+		t.Errorf("Expected 2 transport retries, Got: %v\n", metrics.retries)
+	}
+}
+
+func TestEqualJitterShouldStayWithinBackoff(t *testing.T) {
+
+	backoff := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		wait := equalJitter(backoff)
+		if wait < backoff/2 || wait > backoff {
+			t.Fatalf("Expected wait between %s and %s, Got: %s\n", backoff/2, backoff, wait)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	tests := []struct {
+		scenario string
+		value    string
+		minimum  time.Duration
+		maximum  time.Duration
+	}{
+		{
+			scenario: "Should return zero if not set",
+			value:    "",
+		},
+		{
+			scenario: "Should parse seconds",
+			value:    "3",
+			minimum:  3 * time.Second,
+			maximum:  3 * time.Second,
+		},
+		{
+			scenario: "Should parse an http date",
+			value:    time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat),
+			minimum:  8 * time.Second,
+			maximum:  10 * time.Second,
+		},
+		{
+			scenario: "Should return zero for dates in the past",
+			value:    time.Now().Add(-10 * time.Second).UTC().Format(http.TimeFormat),
+		},
+		{
+			scenario: "Should return zero for invalid values",
+			value:    "soon",
+		},
+	}
+
+	for _, test := range tests {
+		got := parseRetryAfter(test.value)
+		if got < test.minimum || got > test.maximum {
+			t.Errorf("Scenario '%s'. Expected between %s and %s, Got: %s\n", test.scenario, test.minimum, test.maximum, got)
+		}
+	}
+}
+
+func TestIsSecurePasswordShouldRetryPwnedService(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	fake.FailNext(1, http.StatusServiceUnavailable)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var waits []time.Duration
+	retrier := newTestRetrier(Retry{MaxAttempts: 2, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}, noopMetrics{}, &waits)
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", retrier: retrier}
+
+	isSecure, err := pwned.IsSecurePassword(context.Background(), "Passw0rd")
+	if err != nil {
+		t.Errorf("Wasn't expecting error, Got: %v\n", err)
+	}
+	if isSecure {
+		t.Error("Expected password not to be secure")
+	}
+	if requests := fake.Requests(); requests != 2 {
+		t.Errorf("Expected %d requests, Got: %d\n", 2, requests)
+	}
+}
@@ -0,0 +1,163 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxRangeSize caps the range responses read from the pwned service. Padded
+// responses hold around a thousand entries, far below it.
+const maxRangeSize = 1 << 20
+
+type (
+	// rangeScanner reads the SUFFIX:COUNT entries of a range response as it is
+	// received, without holding the whole body in memory.
+	rangeScanner struct {
+		scanner *bufio.Scanner
+		line    int
+		suffix  []byte
+		count   int
+		err     error
+	}
+
+	// cappedReader fails once more than remaining bytes are read from reader.
+	cappedReader struct {
+		reader    io.Reader
+		limit     int64
+		remaining int64
+	}
+)
+
+func newRangeScanner(body io.Reader) *rangeScanner {
+	return &rangeScanner{scanner: bufio.NewScanner(body)}
+}
+
+// Scan advances to the next entry, skipping empty lines. It returns false once
+// the body has been read or a malformed entry is found, see Err.
+func (rs *rangeScanner) Scan() bool {
+
+	for rs.err == nil && rs.scanner.Scan() {
+		rs.line++
+		line := bytes.TrimSpace(rs.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		separator := bytes.IndexByte(line, ':')
+		if separator <= 0 || !isHex(line[:separator]) {
+			rs.err = fmt.Errorf("line %d: malformed range entry '%s'", rs.line, line)
+			return false
+		}
+
+		count, ok := parseCount(bytes.TrimSpace(line[separator+1:]))
+		if !ok {
+			rs.err = fmt.Errorf("line %d: invalid count in range entry '%s'", rs.line, line)
+			return false
+		}
+
+		rs.suffix, rs.count = line[:separator], count
+		return true
+	}
+
+	if rs.err == nil {
+		rs.err = rs.scanner.Err()
+	}
+	return false
+}
+
+// Suffix returns the hash suffix of the current entry. It is only valid until
+// the next call to Scan.
+func (rs *rangeScanner) Suffix() []byte {
+	return rs.suffix
+}
+
+func (rs *rangeScanner) Count() int {
+	return rs.count
+}
+
+func (rs *rangeScanner) Err() error {
+	return rs.err
+}
+
+// readRange reads every entry of a range response, skipping padding entries.
+func readRange(body io.Reader) (rangeSuffixes, error) {
+
+	suffixes := make(rangeSuffixes)
+	scanner := newRangeScanner(body)
+	for scanner.Scan() {
+		// Padding entries are decoys which have never been seen in breaches
+		if scanner.Count() == 0 {
+			continue
+		}
+		suffixes[string(scanner.Suffix())] = scanner.Count()
+	}
+
+	return suffixes, scanner.Err()
+}
+
+// findSuffix reads a range response until it finds suffix, and returns how
+// many times it has been seen.
+func findSuffix(body io.Reader, suffix string) (int, error) {
+
+	target := []byte(suffix)
+	scanner := newRangeScanner(body)
+	for scanner.Scan() {
+		if bytes.Equal(scanner.Suffix(), target) {
+			return scanner.Count(), nil
+		}
+	}
+
+	return 0, scanner.Err()
+}
+
+func newCappedReader(reader io.Reader, limit int64) *cappedReader {
+	return &cappedReader{reader: reader, limit: limit, remaining: limit}
+}
+
+func (cr *cappedReader) Read(p []byte) (int, error) {
+
+	if cr.remaining < 0 {
+		return 0, fmt.Errorf("range response exceeds %d bytes", cr.limit)
+	}
+
+	// Read one byte past the limit to tell a body of exactly limit bytes apart
+	if int64(len(p)) > cr.remaining+1 {
+		p = p[:cr.remaining+1]
+	}
+
+	n, err := cr.reader.Read(p)
+	cr.remaining -= int64(n)
+	if cr.remaining < 0 {
+		return n, fmt.Errorf("range response exceeds %d bytes", cr.limit)
+	}
+	return n, err
+}
+
+func isHex(value []byte) bool {
+	for _, char := range value {
+		if !('0' <= char && char <= '9' || 'A' <= char && char <= 'F' || 'a' <= char && char <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCount parses a decimal count without the allocations of strconv.Atoi
+// over a string conversion.
+func parseCount(value []byte) (int, bool) {
+
+	if len(value) == 0 || len(value) > 10 {
+		return 0, false
+	}
+
+	count := 0
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return 0, false
+		}
+		count = count*10 + int(char-'0')
+	}
+	return count, true
+}
@@ -0,0 +1,236 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingReader fails if read past the given content, telling a scanner went
+// further than needed.
+type failingReader struct {
+	reader io.Reader
+}
+
+func (fr failingReader) Read(p []byte) (int, error) {
+	n, err := fr.reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("read past the expected content")
+	}
+	return n, err
+}
+
+func TestReadRangeShouldParseEntries(t *testing.T) {
+
+	tests := []struct {
+		scenario         string
+		responseBody     string
+		expectedSuffixes rangeSuffixes
+		expectedError    bool
+	}{
+		{
+			scenario:         "Should parse lines ending in \\n",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:5\n078957725007D81F20E2354088A04162EC9:1\n",
+			expectedSuffixes: rangeSuffixes{"0784E50CD59416AFA6E9E22DEBDA9603901": 5, "078957725007D81F20E2354088A04162EC9": 1},
+		},
+		{
+			scenario:         "Should parse lines ending in \\r\\n",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n078957725007D81F20E2354088A04162EC9:1",
+			expectedSuffixes: rangeSuffixes{"0784E50CD59416AFA6E9E22DEBDA9603901": 5, "078957725007D81F20E2354088A04162EC9": 1},
+		},
+		{
+			scenario:         "Should skip empty lines and surrounding spaces",
+			responseBody:     "\r\n  0784E50CD59416AFA6E9E22DEBDA9603901:5 \r\n\r\n",
+			expectedSuffixes: rangeSuffixes{"0784E50CD59416AFA6E9E22DEBDA9603901": 5},
+		},
+		{
+			scenario:         "Should skip padding entries",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n078957725007D81F20E2354088A04162EC9:0\r\n",
+			expectedSuffixes: rangeSuffixes{"0784E50CD59416AFA6E9E22DEBDA9603901": 5},
+		},
+		{
+			scenario:         "Should reject lines without separator",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n<html>\r\n",
+			expectedSuffixes: rangeSuffixes{"0784E50CD59416AFA6E9E22DEBDA9603901": 5},
+			expectedError:    true,
+		},
+		{
+			scenario:         "Should reject suffixes which aren't hexadecimal",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA960390Z:5\r\n",
+			expectedSuffixes: rangeSuffixes{},
+			expectedError:    true,
+		},
+		{
+			scenario:         "Should reject invalid counts",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:-5\r\n",
+			expectedSuffixes: rangeSuffixes{},
+			expectedError:    true,
+		},
+		{
+			scenario:         "Should reject missing counts",
+			responseBody:     "0784E50CD59416AFA6E9E22DEBDA9603901:\r\n",
+			expectedSuffixes: rangeSuffixes{},
+			expectedError:    true,
+		},
+	}
+
+	for _, test := range tests {
+
+		suffixes, err := readRange(strings.NewReader(test.responseBody))
+		if (err != nil) != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error: %t, Got: %v\n", test.scenario, test.expectedError, err)
+		}
+		if len(suffixes) != len(test.expectedSuffixes) {
+			t.Errorf("Scenario '%s'. Expected suffixes %v, Got: %v\n", test.scenario, test.expectedSuffixes, suffixes)
+			continue
+		}
+		for suffix, count := range test.expectedSuffixes {
+			if suffixes[suffix] != count {
+				t.Errorf("Scenario '%s'. Expected suffixes %v, Got: %v\n", test.scenario, test.expectedSuffixes, suffixes)
+				break
+			}
+		}
+	}
+}
+
+func TestFindSuffixShouldStopAtTheMatch(t *testing.T) {
+
+	body := io.MultiReader(
+		strings.NewReader("0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n078957725007D81F20E2354088A04162EC9:12\r\n"),
+		failingReader{strings.NewReader("0790D55E682FDBFDE7DAF7FCAA14BAE6C71:17\r\n")},
+	)
+
+	count, err := findSuffix(body, "078957725007D81F20E2354088A04162EC9")
+	if err != nil {
+		t.Errorf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	if count != 12 {
+		t.Errorf("Expected count %d, Got: %d\n", 12, count)
+	}
+}
+
+func TestCappedReaderShouldLimitTheBody(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		size          int
+		expectedError bool
+	}{
+		{
+			scenario:      "Should read bodies up to the limit",
+			size:          64,
+			expectedError: false,
+		},
+		{
+			scenario:      "Should fail on bodies over the limit",
+			size:          65,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		_, err := io.ReadAll(newCappedReader(strings.NewReader(strings.Repeat("A", test.size)), 64))
+		if (err != nil) != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error: %t, Got: %v\n", test.scenario, test.expectedError, err)
+		}
+	}
+}
+
+func TestCheckShouldFailOnMalformedRanges(t *testing.T) {
+
+	tests := []struct {
+		scenario     string
+		responseBody string
+	}{
+		{
+			scenario:     "Should fail on malformed entries",
+			responseBody: "<html><body>Maintenance</body></html>",
+		},
+		{
+			scenario:     "Should fail on oversized responses",
+			responseBody: strings.Repeat("0784E50CD59416AFA6E9E22DEBDA9603901:5\r\n", maxRangeSize/38),
+		},
+	}
+
+	for _, test := range tests {
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte(test.responseBody))
+		}))
+
+		for _, pwned := range []Pwned{
+			{Enabled: true, Timeout: 2, URL: server.URL + "/"},
+			{Enabled: true, Timeout: 2, URL: server.URL + "/", flights: newFlightGroup()},
+		} {
+			if _, err := pwned.Check(context.Background(), "Passw0rd"); !errors.Is(err, ErrRead) {
+				t.Errorf("Scenario '%s'. Expected a read error, Got: '%v'\n", test.scenario, err)
+			}
+		}
+		server.Close()
+	}
+}
+
+// legacyIsPasswordInDictionary is how ranges were searched before being
+// streamed, on the whole response read with io.ReadAll, kept to compare
+// allocations.
+func legacyIsPasswordInDictionary(passwordSuffix string, responseBody string) bool {
+
+	lines := strings.Split(string(responseBody), "\n")
+	for _, line := range lines {
+
+		if len(line) > 0 && strings.Contains(line, ":") {
+			if result := strings.Compare(passwordSuffix, line[:strings.LastIndex(line, ":")]); result == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// newBenchmarkRange returns a padded range response, with the size of the
+// ones served by the pwned service.
+func newBenchmarkRange() string {
+
+	var body strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&body, "%035X:%d\r\n", i, i%3)
+	}
+	return body.String()
+}
+
+func BenchmarkLegacyIsPasswordInDictionary(b *testing.B) {
+
+	body := newBenchmarkRange()
+	suffix := fmt.Sprintf("%035X", 500)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		response, _ := io.ReadAll(strings.NewReader(body))
+		legacyIsPasswordInDictionary(suffix, string(response))
+	}
+}
+
+func BenchmarkReadRange(b *testing.B) {
+
+	body := newBenchmarkRange()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		readRange(strings.NewReader(body))
+	}
+}
+
+func BenchmarkFindSuffix(b *testing.B) {
+
+	body := newBenchmarkRange()
+	suffix := fmt.Sprintf("%035X", 500)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		findSuffix(strings.NewReader(body), suffix)
+	}
+}
@@ -0,0 +1,170 @@
+package pwned
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// allPrefixes is the number of 5 character hexadecimal hash prefixes.
+const allPrefixes = 1 << 20
+
+type (
+	// WarmUp fetches ranges into the caches in the background, so validations
+	// after a deploy don't wait for the pwned service.
+	WarmUp struct {
+		Enabled bool `yaml:"enabled"`
+		// Prefixes to fetch, every prefix when empty
+		Prefixes          []string      `yaml:"prefixes"`
+		RequestsPerSecond float64       `yaml:"requestsPerSecond"`
+		Concurrency       int           `yaml:"concurrency"`
+		Refresh           time.Duration `yaml:"refreshSeconds"`
+		// ReadyPercent is how much of the prefixes are fetched before the
+		// service reports itself ready, all of them by default.
+		ReadyPercent int `yaml:"readyPercent"`
+		// ReadyTimeout is how long the service waits for ReadyPercent before
+		// reporting itself ready anyway, forever when zero.
+		ReadyTimeout time.Duration `yaml:"readyTimeoutSeconds"`
+	}
+
+	// warmer runs passes over the warm-up prefixes, at most one request every
+	// interval.
+	warmer struct {
+		prefixes     []string
+		interval     time.Duration
+		concurrency  int
+		refresh      time.Duration
+		readyAt      int64
+		readyTimeout time.Duration
+		metrics      MetricService
+		sleep        func(ctx context.Context, wait time.Duration) error
+		now          func() time.Time
+		started      int64
+		processed    int64
+		fetched      int64
+		ready        int32
+	}
+)
+
+func newWarmer(config WarmUp, metrics MetricService) (*warmer, error) {
+
+	warmer := &warmer{
+		concurrency:  config.Concurrency,
+		refresh:      config.Refresh,
+		readyTimeout: config.ReadyTimeout,
+		metrics:      metrics,
+		sleep:        sleepContext,
+		now:          time.Now,
+	}
+	if warmer.concurrency < 1 {
+		warmer.concurrency = 1
+	}
+	if config.RequestsPerSecond > 0 {
+		warmer.interval = time.Duration(float64(time.Second) / config.RequestsPerSecond)
+	}
+
+	for _, prefix := range config.Prefixes {
+		if len(prefix) != 5 || !isHex([]byte(prefix)) {
+			return nil, fmt.Errorf("invalid warm-up prefix '%s'", prefix)
+		}
+		warmer.prefixes = append(warmer.prefixes, strings.ToUpper(prefix))
+	}
+
+	readyPercent := config.ReadyPercent
+	if readyPercent <= 0 || readyPercent > 100 {
+		readyPercent = 100
+	}
+	warmer.readyAt = (int64(warmer.total())*int64(readyPercent) + 99) / 100
+
+	return warmer, nil
+}
+
+// total returns how many prefixes each pass fetches.
+func (w *warmer) total() int {
+	if len(w.prefixes) > 0 {
+		return len(w.prefixes)
+	}
+	return allPrefixes
+}
+
+func (w *warmer) prefix(i int) string {
+	if len(w.prefixes) > 0 {
+		return w.prefixes[i]
+	}
+	return fmt.Sprintf("%05X", i)
+}
+
+// run fetches every prefix, and again every refresh, until ctx is done.
+func (w *warmer) run(ctx context.Context, fetch func(ctx context.Context, prefix string) error) {
+
+	atomic.StoreInt64(&w.started, w.now().UnixNano())
+	for {
+		w.pass(ctx, fetch)
+		if w.refresh <= 0 || w.sleep(ctx, w.refresh) != nil {
+			return
+		}
+	}
+}
+
+func (w *warmer) pass(ctx context.Context, fetch func(ctx context.Context, prefix string) error) {
+
+	atomic.StoreInt64(&w.processed, 0)
+	atomic.StoreInt64(&w.fetched, 0)
+	w.metrics.WarmUpProgress(0, w.total())
+
+	prefixes := make(chan string)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for prefix := range prefixes {
+				err := fetch(ctx, prefix)
+				if err != nil && ctx.Err() == nil {
+					w.metrics.WarmUpFailure()
+				}
+				w.processedPrefix(err == nil)
+			}
+		}()
+	}
+
+	defer waitGroup.Wait()
+	defer close(prefixes)
+	for i := 0; i < w.total(); i++ {
+		if i > 0 && w.interval > 0 && w.sleep(ctx, w.interval) != nil {
+			return
+		}
+		select {
+		case prefixes <- w.prefix(i):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processedPrefix records a prefix fetched, or given up on. Only fetched
+// prefixes count towards the ready threshold, an unreachable pwned service is
+// left to the ready timeout.
+func (w *warmer) processedPrefix(fetched bool) {
+
+	processed := atomic.AddInt64(&w.processed, 1)
+	w.metrics.WarmUpProgress(int(processed), w.total())
+	if fetched && atomic.AddInt64(&w.fetched, 1) >= w.readyAt {
+		atomic.StoreInt32(&w.ready, 1)
+	}
+}
+
+// isReady tells whether a pass has reached the ready threshold, or the ready
+// timeout has passed since the warm-up started. Once ready, later passes only
+// refresh the caches, so they don't change it.
+func (w *warmer) isReady() bool {
+
+	if atomic.LoadInt32(&w.ready) == 1 {
+		return true
+	}
+	started := atomic.LoadInt64(&w.started)
+	return w.readyTimeout > 0 && started != 0 && w.now().Sub(time.Unix(0, started)) >= w.readyTimeout
+}
@@ -0,0 +1,289 @@
+package pwned
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jruben-rg/password-service/go-pwned/pwned/pwnedtest"
+)
+
+func newTestWarmer(t *testing.T, config WarmUp, metrics MetricService, waits *[]time.Duration) *warmer {
+	t.Helper()
+
+	warmer, err := newWarmer(config, metrics)
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+
+	// Record the waits instead of sleeping, stopping at the first refresh
+	warmer.sleep = func(ctx context.Context, wait time.Duration) error {
+		*waits = append(*waits, wait)
+		if wait == warmer.refresh {
+			return context.Canceled
+		}
+		return nil
+	}
+	return warmer
+}
+
+func TestNewWarmerShouldValidatePrefixes(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		prefixes      []string
+		expectedTotal int
+		expectedFirst string
+		expectedLast  string
+		expectedError bool
+	}{
+		{
+			scenario:      "Should fetch every prefix if none is configured",
+			expectedTotal: 1 << 20,
+			expectedFirst: "00000",
+			expectedLast:  "FFFFF",
+		},
+		{
+			scenario:      "Should fetch the configured prefixes in upper case",
+			prefixes:      []string{"21bd1", "EBFC7"},
+			expectedTotal: 2,
+			expectedFirst: "21BD1",
+			expectedLast:  "EBFC7",
+		},
+		{
+			scenario:      "Should reject prefixes which aren't 5 characters long",
+			prefixes:      []string{"21BD"},
+			expectedError: true,
+		},
+		{
+			scenario:      "Should reject prefixes which aren't hexadecimal",
+			prefixes:      []string{"21BDZ"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		warmer, err := newWarmer(WarmUp{Prefixes: test.prefixes}, noopMetrics{})
+		if (err != nil) != test.expectedError {
+			t.Errorf("Scenario '%s'. Expected error: %t, Got: %v\n", test.scenario, test.expectedError, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if total := warmer.total(); total != test.expectedTotal {
+			t.Errorf("Scenario '%s'. Expected %d prefixes, Got: %d\n", test.scenario, test.expectedTotal, total)
+		}
+		if first := warmer.prefix(0); first != test.expectedFirst {
+			t.Errorf("Scenario '%s'. Expected first prefix '%s', Got: '%s'\n", test.scenario, test.expectedFirst, first)
+		}
+		if last := warmer.prefix(warmer.total() - 1); last != test.expectedLast {
+			t.Errorf("Scenario '%s'. Expected last prefix '%s', Got: '%s'\n", test.scenario, test.expectedLast, last)
+		}
+	}
+}
+
+func TestWarmerShouldBeReadyAtThreshold(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		readyPercent  int
+		fetched       int
+		expectedReady bool
+	}{
+		{
+			scenario:      "Should not be ready below the threshold",
+			readyPercent:  50,
+			fetched:       1,
+			expectedReady: false,
+		},
+		{
+			scenario:      "Should be ready at the threshold",
+			readyPercent:  50,
+			fetched:       2,
+			expectedReady: true,
+		},
+		{
+			scenario:      "Should wait for every prefix by default",
+			fetched:       3,
+			expectedReady: false,
+		},
+	}
+
+	for _, test := range tests {
+
+		var waits []time.Duration
+		warmer := newTestWarmer(t, WarmUp{Prefixes: []string{"00000", "00001", "00002", "00003"}, ReadyPercent: test.readyPercent}, noopMetrics{}, &waits)
+
+		// Fetches past the expected ones never finish
+		ctx, cancel := context.WithCancel(context.Background())
+		var fetched int32
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			warmer.run(ctx, func(ctx context.Context, prefix string) error {
+				if int(atomic.AddInt32(&fetched, 1)) > test.fetched {
+					<-release
+				}
+				return nil
+			})
+			close(done)
+		}()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt64(&warmer.fetched) < int64(test.fetched) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+
+		if ready := warmer.isReady(); ready != test.expectedReady {
+			t.Errorf("Scenario '%s'. Expected ready: %t, Got: %t\n", test.scenario, test.expectedReady, ready)
+		}
+		cancel()
+		close(release)
+		<-done
+	}
+}
+
+func TestWarmerShouldLimitRateAndRefresh(t *testing.T) {
+
+	var waits []time.Duration
+	metrics := &testMetrics{}
+	warmer := newTestWarmer(t, WarmUp{Prefixes: []string{"00000", "00001", "00002"}, RequestsPerSecond: 4, Refresh: time.Minute}, metrics, &waits)
+
+	var mutex sync.Mutex
+	var fetched []string
+	warmer.run(context.Background(), func(ctx context.Context, prefix string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		fetched = append(fetched, prefix)
+		if prefix == "00001" {
+			return errTestUpstream
+		}
+		return nil
+	})
+
+	// Two passes, the second one interrupted by the test sleep
+	expectedWaits := []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, time.Minute}
+	if len(waits) != len(expectedWaits) {
+		t.Fatalf("Expected waits %v, Got: %v\n", expectedWaits, waits)
+	}
+	for i := range expectedWaits {
+		if waits[i] != expectedWaits[i] {
+			t.Errorf("Expected waits %v, Got: %v\n", expectedWaits, waits)
+			break
+		}
+	}
+	if len(fetched) != 3 {
+		t.Errorf("Expected %d prefixes fetched, Got: %v\n", 3, fetched)
+	}
+	if metrics.warmUpFailures != 1 || metrics.warmUpProgress != 3 {
+		t.Errorf("Expected %d failure and %d prefixes processed, Got: %d and %d\n", 1, 3, metrics.warmUpFailures, metrics.warmUpProgress)
+	}
+	if warmer.isReady() {
+		t.Error("Wasn't expecting failed prefixes to count towards readiness")
+	}
+}
+
+func TestWarmerShouldWaitForTheReadyTimeoutIfFetchesFail(t *testing.T) {
+
+	tests := []struct {
+		scenario      string
+		readyTimeout  time.Duration
+		elapsed       time.Duration
+		expectedReady bool
+	}{
+		{
+			scenario:      "Should not be ready without a ready timeout",
+			elapsed:       time.Hour,
+			expectedReady: false,
+		},
+		{
+			scenario:      "Should not be ready before the ready timeout",
+			readyTimeout:  time.Minute,
+			elapsed:       59 * time.Second,
+			expectedReady: false,
+		},
+		{
+			scenario:      "Should be ready after the ready timeout",
+			readyTimeout:  time.Minute,
+			elapsed:       time.Minute,
+			expectedReady: true,
+		},
+	}
+
+	for _, test := range tests {
+
+		var waits []time.Duration
+		metrics := &testMetrics{}
+		warmer := newTestWarmer(t, WarmUp{Prefixes: []string{"00000", "00001"}, ReadyTimeout: test.readyTimeout}, metrics, &waits)
+		now := time.Now()
+		warmer.now = func() time.Time { return now }
+
+		warmer.run(context.Background(), func(ctx context.Context, prefix string) error {
+			return errTestUpstream
+		})
+		if warmer.isReady() {
+			t.Errorf("Scenario '%s'. Wasn't expecting to be ready after every fetch failed\n", test.scenario)
+		}
+		if metrics.warmUpFailures != 2 {
+			t.Errorf("Scenario '%s'. Expected %d failures, Got: %d\n", test.scenario, 2, metrics.warmUpFailures)
+		}
+
+		now = now.Add(test.elapsed)
+		if ready := warmer.isReady(); ready != test.expectedReady {
+			t.Errorf("Scenario '%s'. Expected ready: %t, Got: %t\n", test.scenario, test.expectedReady, ready)
+		}
+	}
+}
+
+func TestStartWarmUpShouldFillTheCache(t *testing.T) {
+
+	fake := pwnedtest.NewServer()
+	fake.Add("Passw0rd", 4)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	warmer, err := newWarmer(WarmUp{Prefixes: []string{"21BD1", "EBFC7"}}, noopMetrics{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting error, Got: '%s'\n", err)
+	}
+	cache := newRangeCache(time.Minute, 0, noopMetrics{})
+	pwned := Pwned{Enabled: true, Timeout: 2, URL: server.URL + "/range/", cache: cache, warmer: warmer}
+
+	if pwned.Ready() {
+		t.Error("Wasn't expecting to be ready before the warm-up")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pwned.StartWarmUp(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !pwned.Ready() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !pwned.Ready() {
+		t.Fatal("Expected to be ready once the warm-up is done")
+	}
+
+	// "Passw0rd" has the SHA-1 prefix EBFC7, which is already cached
+	secure, err := pwned.IsSecurePassword(context.Background(), "Passw0rd")
+	if err != nil || secure {
+		t.Errorf("Expected an insecure password without error, Got: %t, %v\n", secure, err)
+	}
+	if requests := fake.Requests(); requests != 2 || cache.len() != 2 {
+		t.Errorf("Expected %d requests and %d cached prefixes, Got: %d and %d\n", 2, 2, requests, cache.len())
+	}
+}
+
+func TestReadyShouldBeTrueWithoutWarmUp(t *testing.T) {
+
+	pwned := Pwned{Enabled: true}
+	if !pwned.Ready() {
+		t.Error("Expected to be ready without warm-up")
+	}
+}